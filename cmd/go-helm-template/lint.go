@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/slok/go-helm-template/helm"
+)
+
+func runLint(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	var vFlags valuesFlags
+
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Check a chart for possible issues.\n\nUsage:\n  go-helm-template lint CHART_PATH [flags]\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	vFlags.register(fs)
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usageError{err: fmt.Errorf("lint requires CHART_PATH argument")}
+	}
+	chartPath := positional[0]
+
+	vals, err := vFlags.merge()
+	if err != nil {
+		return err
+	}
+
+	chart, err := helm.LoadChart(ctx, os.DirFS(chartPath))
+	if err != nil {
+		return fmt.Errorf("lint failed: %w", err)
+	}
+
+	result, err := helm.Lint(ctx, chart, vals)
+	if err != nil {
+		return fmt.Errorf("lint failed: %w", err)
+	}
+
+	for _, f := range result.Findings {
+		fmt.Fprintln(stdout, f)
+	}
+
+	failed := 0
+	if result.HasErrors() {
+		failed = 1
+	}
+	fmt.Fprintf(stdout, "%s: 1 chart linted, %d chart failed\n", chartPath, failed)
+
+	if failed > 0 {
+		return fmt.Errorf("lint failed: chart has errors")
+	}
+
+	return nil
+}
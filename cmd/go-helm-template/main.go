@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Exit codes.
+const (
+	exitCodeOK    = 0
+	exitCodeError = 1
+	exitCodeUsage = 2
+)
+
+const usage = `go-helm-template renders Helm charts without the Helm binary.
+
+Usage:
+  go-helm-template <command> [flags] <args>
+
+Commands:
+  template     Render a chart templates.
+  lint         Check a chart for possible issues.
+  show values  Show the chart default values.
+  show chart   Show the chart definition.
+
+Exit codes:
+  0  Success.
+  1  Command failed (e.g: render error, lint issues).
+  2  Invalid usage (e.g: unknown command, invalid flags).
+
+Use "go-helm-template <command> -h" for more information about a command.
+`
+
+// usageError is an error caused by an invalid command usage.
+type usageError struct {
+	err error
+}
+
+func (u usageError) Error() string { return u.err.Error() }
+func (u usageError) Unwrap() error { return u.err }
+
+// Example:
+//
+//	go run ./cmd/go-helm-template template my-release ./examples/chart-unit-test/some-chart --set labels.k1=v1.
+func main() {
+	ctx := context.Background()
+	os.Exit(run(ctx, os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return exitCodeUsage
+	}
+
+	var err error
+	switch cmd, cmdArgs := args[0], args[1:]; cmd {
+	case "template":
+		err = runTemplate(ctx, cmdArgs, stdout, stderr)
+	case "lint":
+		err = runLint(ctx, cmdArgs, stdout, stderr)
+	case "show":
+		err = runShow(ctx, cmdArgs, stdout, stderr)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return exitCodeOK
+	default:
+		err = usageError{err: fmt.Errorf("unknown command %q", cmd)}
+	}
+
+	switch {
+	case err == nil:
+		return exitCodeOK
+	case errors.Is(err, flag.ErrHelp):
+		return exitCodeOK
+	case errors.As(err, &usageError{}):
+		fmt.Fprintf(stderr, "Error: %s\n\n", err)
+		fmt.Fprint(stderr, usage)
+		return exitCodeUsage
+	default:
+		fmt.Fprintf(stderr, "Error: %s\n", err)
+		return exitCodeError
+	}
+}
+
+// parseFlags parses the flags allowing them to be placed before, after or between the
+// positional arguments, it returns the positional arguments.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	positional := []string{}
+	for {
+		err := fs.Parse(args)
+		if err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, usageError{err: err}
+		}
+
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// stringsFlag is a flag that can be set multiple times.
+type stringsFlag []string
+
+func (s *stringsFlag) String() string { return strings.Join(*s, ",") }
+
+func (s *stringsFlag) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
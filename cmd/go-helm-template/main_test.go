@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	tests := map[string]struct {
+		args        []string
+		expExitCode int
+		expStdout   string
+	}{
+		"Without command should fail with usage exit code.": {
+			args:        []string{},
+			expExitCode: exitCodeUsage,
+		},
+
+		"An unknown command should fail with usage exit code.": {
+			args:        []string{"unknown"},
+			expExitCode: exitCodeUsage,
+		},
+
+		"Template should render the chart.": {
+			args:        []string{"template", "testdata/chart"},
+			expExitCode: exitCodeOK,
+			expStdout:   "---\n# Source: test-chart/templates/configmap.yaml\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: default\n  namespace: default\ndata:\n  release: release-name\n",
+		},
+
+		"Template should render the chart with the release name, namespace and values.": {
+			args:        []string{"template", "test", "testdata/chart", "--set", "name=custom", "-n", "test-ns"},
+			expExitCode: exitCodeOK,
+			expStdout:   "---\n# Source: test-chart/templates/configmap.yaml\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: custom\n  namespace: test-ns\ndata:\n  release: test\n",
+		},
+
+		"Template should render the chart hooks if enabled.": {
+			args:        []string{"template", "testdata/chart", "--hooks"},
+			expExitCode: exitCodeOK,
+			expStdout:   "---\n# Source: test-chart/templates/configmap.yaml\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: default\n  namespace: default\ndata:\n  release: release-name\n---\n# Source: test-chart/templates/hook.yaml\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: default-hook\n  annotations:\n    helm.sh/hook: pre-install\n",
+		},
+
+		"Template should render the chart in JSON.": {
+			args:        []string{"template", "testdata/chart", "-o", "json"},
+			expExitCode: exitCodeOK,
+			expStdout: `[
+  {
+    "apiVersion": "v1",
+    "data": {
+      "release": "release-name"
+    },
+    "kind": "ConfigMap",
+    "metadata": {
+      "name": "default",
+      "namespace": "default"
+    }
+  }
+]
+`,
+		},
+
+		"Template should validate the rendered manifests if enabled.": {
+			args:        []string{"template", "testdata/chart", "--validate", "--hooks"},
+			expExitCode: exitCodeOK,
+			expStdout:   "---\n# Source: test-chart/templates/configmap.yaml\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: default\n  namespace: default\ndata:\n  release: release-name\n---\n# Source: test-chart/templates/hook.yaml\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: default-hook\n  annotations:\n    helm.sh/hook: pre-install\n",
+		},
+
+		"Template with invalid manifests should fail on validation.": {
+			args:        []string{"template", "testdata/chart", "--validate", "--set", "extra.spec=x"},
+			expExitCode: exitCodeError,
+		},
+
+		"Template with a Kubernetes version without schemas should fail with usage exit code.": {
+			args:        []string{"template", "testdata/chart", "--validate", "--kube-version", "1.29"},
+			expExitCode: exitCodeUsage,
+		},
+
+		"Template on strict mode should render the chart if all the values exist.": {
+			args:        []string{"template", "testdata/chart", "--strict", "--set", "extra.immutable=true"},
+			expExitCode: exitCodeOK,
+			expStdout:   "---\n# Source: test-chart/templates/configmap.yaml\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: default\n  namespace: default\ndata:\n  release: release-name\nimmutable: true\n",
+		},
+
+		"Template on strict mode should fail on missing values.": {
+			args:        []string{"template", "testdata/chart", "--strict"},
+			expExitCode: exitCodeError,
+		},
+
+		"Template with an invalid output format should fail with usage exit code.": {
+			args:        []string{"template", "testdata/chart", "-o", "xml"},
+			expExitCode: exitCodeUsage,
+		},
+
+		"Template with a missing chart should fail.": {
+			args:        []string{"template", "testdata/missing"},
+			expExitCode: exitCodeError,
+		},
+
+		"Lint should lint the chart.": {
+			args:        []string{"lint", "testdata/chart"},
+			expExitCode: exitCodeOK,
+			expStdout:   "[INFO] Chart.yaml: icon is recommended (chartfile)\ntestdata/chart: 1 chart linted, 0 chart failed\n",
+		},
+
+		"Show values should return the chart values.": {
+			args:        []string{"show", "values", "testdata/chart"},
+			expExitCode: exitCodeOK,
+			expStdout:   "# Default values.\nname: default\n",
+		},
+
+		"Show chart should return the chart definition.": {
+			args:        []string{"show", "chart", "testdata/chart"},
+			expExitCode: exitCodeOK,
+			expStdout:   "apiVersion: v2\nname: test-chart\nversion: 0.1.0\n",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var stdout, stderr bytes.Buffer
+			gotExitCode := run(context.TODO(), test.args, &stdout, &stderr)
+
+			assert.Equal(test.expExitCode, gotExitCode)
+			if test.expExitCode == exitCodeOK {
+				assert.Equal(test.expStdout, stdout.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/slok/go-helm-template/helm"
+)
+
+func runShow(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		return usageError{err: fmt.Errorf("show requires a subcommand (values, chart)")}
+	}
+
+	var file string
+	switch args[0] {
+	case "values":
+		file = "values.yaml"
+	case "chart":
+		file = "Chart.yaml"
+	default:
+		return usageError{err: fmt.Errorf("unknown show subcommand %q", args[0])}
+	}
+
+	flags := flag.NewFlagSet("show "+args[0], flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Show the chart %s file.\n\nUsage:\n  go-helm-template show %s CHART_PATH\n", file, args[0])
+	}
+
+	positional, err := parseFlags(flags, args[1:])
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usageError{err: fmt.Errorf("show %s requires CHART_PATH argument", args[0])}
+	}
+
+	chartFS := os.DirFS(positional[0])
+
+	// Make sure is a valid chart.
+	_, err = helm.LoadChart(ctx, chartFS)
+	if err != nil {
+		return err
+	}
+
+	data, err := fs.ReadFile(chartFS, file)
+	if err != nil {
+		// Values are optional.
+		if file == "values.yaml" && os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("could not read %s: %w", file, err)
+	}
+
+	_, err = stdout.Write(data)
+	return err
+}
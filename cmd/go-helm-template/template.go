@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"helm.sh/helm/v4/pkg/cli/values"
+	"helm.sh/helm/v4/pkg/getter"
+	releaseutilv1 "helm.sh/helm/v4/pkg/release/v1/util"
+	"sigs.k8s.io/yaml"
+
+	"github.com/slok/go-helm-template/helm"
+)
+
+const (
+	defaultReleaseName = "release-name"
+	defaultNamespace   = "default"
+
+	outputFormatYAML = "yaml"
+	outputFormatJSON = "json"
+)
+
+// valuesFlags are the flags used to set the chart values.
+type valuesFlags struct {
+	valueFiles   stringsFlag
+	values       stringsFlag
+	stringValues stringsFlag
+	fileValues   stringsFlag
+	jsonValues   stringsFlag
+}
+
+func (v *valuesFlags) register(fs *flag.FlagSet) {
+	fs.Var(&v.valueFiles, "values", "values YAML file (can be repeated, `-` for stdin)")
+	fs.Var(&v.valueFiles, "f", "shorthand for -values")
+	fs.Var(&v.values, "set", "set values (can be repeated, e.g: key1=val1,key2=val2)")
+	fs.Var(&v.stringValues, "set-string", "set STRING values (can be repeated, e.g: key1=val1,key2=val2)")
+	fs.Var(&v.fileValues, "set-file", "set values from files (can be repeated, e.g: key1=path1,key2=path2)")
+	fs.Var(&v.jsonValues, "set-json", "set JSON values (can be repeated, e.g: key1=jsonval1,key2=jsonval2)")
+}
+
+// values returns the values merged the same way Helm does, files are merged in order and then the `set` flags
+// are applied. Remote files are not supported.
+func (v *valuesFlags) merge() (map[string]interface{}, error) {
+	opts := values.Options{
+		ValueFiles:   v.valueFiles,
+		Values:       v.values,
+		StringValues: v.stringValues,
+		FileValues:   v.fileValues,
+		JSONValues:   v.jsonValues,
+	}
+
+	vals, err := opts.MergeValues(getter.Providers{})
+	if err != nil {
+		return nil, fmt.Errorf("could not load values: %w", err)
+	}
+
+	return vals, nil
+}
+
+func runTemplate(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	var (
+		vFlags      valuesFlags
+		namespace   string
+		showFiles   stringsFlag
+		includeCRDs bool
+		enableHooks bool
+		isUpgrade   bool
+		output      string
+		validate    bool
+		schemasDir  string
+		kubeVersion string
+		strict      bool
+	)
+
+	fs := flag.NewFlagSet("template", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Render a chart templates.\n\nUsage:\n  go-helm-template template [NAME] CHART_PATH [flags]\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	vFlags.register(fs)
+	fs.StringVar(&namespace, "namespace", defaultNamespace, "namespace of the release")
+	fs.StringVar(&namespace, "n", defaultNamespace, "shorthand for -namespace")
+	fs.Var(&showFiles, "show-only", "only render the provided template files (can be repeated, e.g: templates/deployment.yaml)")
+	fs.Var(&showFiles, "s", "shorthand for -show-only")
+	fs.BoolVar(&includeCRDs, "include-crds", false, "include CRDs in the rendered output")
+	fs.BoolVar(&enableHooks, "hooks", false, "include hooks in the rendered output")
+	fs.BoolVar(&isUpgrade, "is-upgrade", false, "render as a release upgrade instead of an install")
+	fs.StringVar(&output, "output", outputFormatYAML, "output format (yaml, json)")
+	fs.StringVar(&output, "o", outputFormatYAML, "shorthand for -output")
+	fs.BoolVar(&validate, "validate", false, "validate the rendered manifests against the Kubernetes schemas")
+	fs.StringVar(&schemasDir, "schemas-dir", "", "directory with Kubernetes OpenAPI documents per version used on the validation (e.g: v1.29/swagger.json)")
+	fs.StringVar(&kubeVersion, "kube-version", "", "Kubernetes version of the -schemas-dir documents used on the validation (requires -schemas-dir)")
+	fs.BoolVar(&strict, "strict", false, "fail when the templates reference missing values")
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	name := defaultReleaseName
+	var chartPath string
+	switch len(positional) {
+	case 1:
+		chartPath = positional[0]
+	case 2:
+		name, chartPath = positional[0], positional[1]
+	default:
+		return usageError{err: fmt.Errorf("template requires [NAME] CHART_PATH arguments")}
+	}
+
+	if output != outputFormatYAML && output != outputFormatJSON {
+		return usageError{err: fmt.Errorf("unknown output format %q", output)}
+	}
+
+	if kubeVersion != "" && schemasDir == "" {
+		return usageError{err: fmt.Errorf("-kube-version requires -schemas-dir")}
+	}
+
+	vals, err := vFlags.merge()
+	if err != nil {
+		return err
+	}
+
+	chart, err := helm.LoadChart(ctx, os.DirFS(chartPath))
+	if err != nil {
+		return err
+	}
+
+	var validation *helm.ValidationConfig
+	if validate {
+		validation = &helm.ValidationConfig{KubeVersion: kubeVersion}
+		if schemasDir != "" {
+			validation.SchemasFS = os.DirFS(schemasDir)
+		}
+	}
+
+	missingValues := helm.MissingValuesModeIgnore
+	if strict {
+		missingValues = helm.MissingValuesModeError
+	}
+
+	result, err := helm.Template(ctx, helm.TemplateConfig{
+		Chart:         chart,
+		ReleaseName:   name,
+		Namespace:     namespace,
+		Values:        vals,
+		ShowFiles:     showFiles,
+		IncludeCRDs:   includeCRDs,
+		EnableHooks:   enableHooks,
+		IsUpgrade:     isUpgrade,
+		Validation:    validation,
+		MissingValues: missingValues,
+	})
+	if err != nil {
+		return err
+	}
+
+	switch output {
+	case outputFormatJSON:
+		return writeManifestsJSON(stdout, result)
+	default:
+		// The hooks are joined without the trailing new line.
+		if result != "" && !strings.HasSuffix(result, "\n") {
+			result += "\n"
+		}
+		_, err := fmt.Fprint(stdout, result)
+		return err
+	}
+}
+
+// writeManifestsJSON writes the YAML manifests stream as a JSON list of objects.
+func writeManifestsJSON(w io.Writer, manifests string) error {
+	split := releaseutilv1.SplitManifests(manifests)
+	keys := make([]string, 0, len(split))
+	for k := range split {
+		keys = append(keys, k)
+	}
+	sort.Sort(releaseutilv1.BySplitManifestsOrder(keys))
+
+	objs := []json.RawMessage{}
+	for _, k := range keys {
+		data, err := yaml.YAMLToJSON([]byte(split[k]))
+		if err != nil {
+			return fmt.Errorf("could not convert manifest to JSON: %w", err)
+		}
+
+		// Empty documents (e.g: only comments).
+		if string(data) == "null" {
+			continue
+		}
+		objs = append(objs, data)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(objs)
+}
@@ -0,0 +1,198 @@
+package helm
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"reflect"
+	"sync"
+
+	"helm.sh/helm/v4/pkg/chart/loader/archive"
+	chartv2 "helm.sh/helm/v4/pkg/chart/v2"
+)
+
+const defaultChartCacheMaxCharts = 100
+
+// ChartCacheConfig is the configuration of the chart cache.
+type ChartCacheConfig struct {
+	// MaxCharts is the maximum number of cached charts, when the cache is full the least
+	// recently used chart is evicted.
+	// By default 100.
+	MaxCharts int
+	// KeyByFS identifies the charts by their fs.FS instead of the content digest, this avoids reading
+	// the chart files on every load (with the content digest the cache only saves the parsing). The fs.FS
+	// content must not change (e.g: `embed.FS`) and the fs.FS must be comparable (e.g: `embed.FS`,
+	// `os.DirFS`, `fs.Sub` of them), otherwise the content digest is used.
+	KeyByFS bool
+}
+
+func (c *ChartCacheConfig) defaults() error {
+	if c.MaxCharts < 0 {
+		return fmt.Errorf("max charts can't be negative")
+	}
+
+	if c.MaxCharts == 0 {
+		c.MaxCharts = defaultChartCacheMaxCharts
+	}
+
+	return nil
+}
+
+// ChartCache caches the loaded charts so loading the same chart multiple times is cheap, by default
+// the charts are identified by their content digest.
+//
+// With the default content digest key all the chart files are still read on every load, the cache only
+// saves the chart parsing (templates, values, dependencies...), not the I/O. Use `KeyByFS` to avoid
+// reading the files when the fs.FS content doesn't change.
+//
+// The cached charts are shared, it's safe to use the cache and the charts concurrently.
+type ChartCache struct {
+	cfg     ChartCacheConfig
+	mu      sync.Mutex
+	lru     *list.List
+	entries map[interface{}]*list.Element
+}
+
+type chartCacheEntry struct {
+	key   interface{}
+	chart *chartv2.Chart
+}
+
+// NewChartCache returns a new chart cache.
+func NewChartCache(config ChartCacheConfig) (*ChartCache, error) {
+	err := config.defaults()
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return &ChartCache{
+		cfg:     config,
+		lru:     list.New(),
+		entries: map[interface{}]*list.Element{},
+	}, nil
+}
+
+// Len returns the number of cached charts.
+func (c *ChartCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.lru.Len()
+}
+
+// Invalidate removes the chart of the fs.FS from the cache, when keyed by content, the chart
+// loaded with the default load options.
+func (c *ChartCache) Invalidate(f fs.FS) error {
+	key, _, err := c.key(context.Background(), f, readChartFilesOptions{}, discardLogger)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for k, e := range c.entries {
+		// The fs.FS charts can be loaded with different options.
+		if fk, ok := k.(fsChartCacheKey); (ok && fk.fs == f) || k == key {
+			c.lru.Remove(e)
+			delete(c.entries, k)
+		}
+	}
+
+	return nil
+}
+
+// Purge removes all the cached charts.
+func (c *ChartCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.lru.Init()
+	c.entries = map[interface{}]*list.Element{}
+}
+
+// fsChartCacheKey identifies a chart by its fs.FS.
+type fsChartCacheKey struct {
+	fs   fs.FS
+	opts readChartFilesOptions
+}
+
+// key returns the cache key of the chart, it also returns the chart files if they have been read.
+func (c *ChartCache) key(ctx context.Context, f fs.FS, opts readChartFilesOptions, logger *slog.Logger) (interface{}, []*archive.BufferedFile, error) {
+	// The options can have a fs.FS too.
+	if key := (fsChartCacheKey{fs: f, opts: opts}); c.cfg.KeyByFS && reflect.ValueOf(key).Comparable() {
+		return key, nil, nil
+	}
+
+	// The ignored files are not part of the content, so the key depends on the options.
+	files, err := readChartFiles(ctx, f, opts, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return filesDigest(files), files, nil
+}
+
+// load returns the cached chart of the fs.FS, or loads it and caches it if missing, it also returns
+// if the chart was cached.
+func (c *ChartCache) load(ctx context.Context, f fs.FS, opts readChartFilesOptions, logger *slog.Logger, loadFiles func([]*archive.BufferedFile, *slog.Logger) (*chartv2.Chart, error)) (*chartv2.Chart, bool, error) {
+	key, files, err := c.key(ctx, f, opts, logger)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if chart, ok := c.get(key); ok {
+		return chart, true, nil
+	}
+
+	if files == nil {
+		files, err = readChartFiles(ctx, f, opts, logger)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	// Concurrent loads of the same chart could load it multiple times, but the result is the same.
+	chart, err := loadFiles(files, logger)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return c.add(key, chart), false, nil
+}
+
+func (c *ChartCache) get(key interface{}) (*chartv2.Chart, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.lru.MoveToFront(e)
+
+	return e.Value.(*chartCacheEntry).chart, true
+}
+
+// add caches the chart returning the cached one, this can be a different one if the chart
+// has been cached meanwhile.
+func (c *ChartCache) add(key interface{}, chart *chartv2.Chart) *chartv2.Chart {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(e)
+		return e.Value.(*chartCacheEntry).chart
+	}
+
+	c.entries[key] = c.lru.PushFront(&chartCacheEntry{key: key, chart: chart})
+	for c.lru.Len() > c.cfg.MaxCharts {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*chartCacheEntry).key)
+	}
+
+	return chart
+}
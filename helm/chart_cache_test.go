@@ -0,0 +1,218 @@
+package helm_test
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/go-helm-template/helm"
+)
+
+func newTestCacheChartFS(name string) fstest.MapFS {
+	chartFS := newTestChartFS()
+	chartFS["templates/cm.yaml"] = &fstest.MapFile{Data: []byte("name: " + name)}
+
+	return chartFS
+}
+
+func writeTestCacheChartDir(t *testing.T, dir, name string) {
+	for path, f := range newTestCacheChartFS(name) {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(path)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, path), f.Data, 0o644))
+	}
+}
+
+func renderCachedChart(t *testing.T, cache *helm.ChartCache, f fs.FS) string {
+	chart, err := helm.LoadChartWithConfig(context.TODO(), f, helm.LoadChartConfig{Cache: cache})
+	require.NoError(t, err)
+
+	out, err := helm.Template(context.TODO(), helm.TemplateConfig{ReleaseName: "test", Chart: chart})
+	require.NoError(t, err)
+
+	return out
+}
+
+func TestChartCache(t *testing.T) {
+	tests := map[string]struct {
+		config     helm.ChartCacheConfig
+		exec       func(t *testing.T, cache *helm.ChartCache) []string
+		expRenders []string
+		expLen     int
+	}{
+		"Charts with the same content should be cached once.": {
+			exec: func(t *testing.T, cache *helm.ChartCache) []string {
+				return []string{
+					renderCachedChart(t, cache, newTestCacheChartFS("a")),
+					renderCachedChart(t, cache, newTestCacheChartFS("a")),
+					renderCachedChart(t, cache, newTestCacheChartFS("b")),
+				}
+			},
+			expRenders: []string{
+				"---\n# Source: test-chart/templates/cm.yaml\nname: a\n",
+				"---\n# Source: test-chart/templates/cm.yaml\nname: a\n",
+				"---\n# Source: test-chart/templates/cm.yaml\nname: b\n",
+			},
+			expLen: 2,
+		},
+
+		"The least recently used charts should be evicted when the cache is full.": {
+			config: helm.ChartCacheConfig{MaxCharts: 2},
+			exec: func(t *testing.T, cache *helm.ChartCache) []string {
+				return []string{
+					renderCachedChart(t, cache, newTestCacheChartFS("a")),
+					renderCachedChart(t, cache, newTestCacheChartFS("b")),
+					renderCachedChart(t, cache, newTestCacheChartFS("c")),
+				}
+			},
+			expRenders: []string{
+				"---\n# Source: test-chart/templates/cm.yaml\nname: a\n",
+				"---\n# Source: test-chart/templates/cm.yaml\nname: b\n",
+				"---\n# Source: test-chart/templates/cm.yaml\nname: c\n",
+			},
+			expLen: 2,
+		},
+
+		"Charts keyed by FS should be cached even if their content changes.": {
+			config: helm.ChartCacheConfig{KeyByFS: true},
+			exec: func(t *testing.T, cache *helm.ChartCache) []string {
+				dir := t.TempDir()
+				writeTestCacheChartDir(t, dir, "a")
+				r1 := renderCachedChart(t, cache, os.DirFS(dir))
+				writeTestCacheChartDir(t, dir, "b")
+				r2 := renderCachedChart(t, cache, os.DirFS(dir))
+				return []string{r1, r2}
+			},
+			expRenders: []string{
+				"---\n# Source: test-chart/templates/cm.yaml\nname: a\n",
+				"---\n# Source: test-chart/templates/cm.yaml\nname: a\n",
+			},
+			expLen: 1,
+		},
+
+		"Charts keyed by FS should be keyed by content when the FS is not comparable.": {
+			config: helm.ChartCacheConfig{KeyByFS: true},
+			exec: func(t *testing.T, cache *helm.ChartCache) []string {
+				return []string{
+					renderCachedChart(t, cache, newTestCacheChartFS("a")),
+					renderCachedChart(t, cache, newTestCacheChartFS("b")),
+				}
+			},
+			expRenders: []string{
+				"---\n# Source: test-chart/templates/cm.yaml\nname: a\n",
+				"---\n# Source: test-chart/templates/cm.yaml\nname: b\n",
+			},
+			expLen: 2,
+		},
+
+		"Charts keyed by FS loaded with different ignore patterns should be cached separately.": {
+			config: helm.ChartCacheConfig{KeyByFS: true},
+			exec: func(t *testing.T, cache *helm.ChartCache) []string {
+				dir := t.TempDir()
+				writeTestCacheChartDir(t, dir, "a")
+				r1 := renderCachedChart(t, cache, os.DirFS(dir))
+
+				chart, err := helm.LoadChartWithConfig(context.TODO(), os.DirFS(dir), helm.LoadChartConfig{Cache: cache, IgnorePatterns: []string{"templates/"}})
+				require.NoError(t, err)
+				r2, err := helm.Template(context.TODO(), helm.TemplateConfig{ReleaseName: "test", Chart: chart})
+				require.NoError(t, err)
+
+				return []string{r1, r2}
+			},
+			expRenders: []string{
+				"---\n# Source: test-chart/templates/cm.yaml\nname: a\n",
+				"",
+			},
+			expLen: 2,
+		},
+
+		"Invalidated charts should be loaded again.": {
+			config: helm.ChartCacheConfig{KeyByFS: true},
+			exec: func(t *testing.T, cache *helm.ChartCache) []string {
+				dir := t.TempDir()
+				writeTestCacheChartDir(t, dir, "a")
+				r1 := renderCachedChart(t, cache, os.DirFS(dir))
+				writeTestCacheChartDir(t, dir, "b")
+				require.NoError(t, cache.Invalidate(os.DirFS(dir)))
+				r2 := renderCachedChart(t, cache, os.DirFS(dir))
+				return []string{r1, r2}
+			},
+			expRenders: []string{
+				"---\n# Source: test-chart/templates/cm.yaml\nname: a\n",
+				"---\n# Source: test-chart/templates/cm.yaml\nname: b\n",
+			},
+			expLen: 1,
+		},
+
+		"Purged caches should be empty.": {
+			exec: func(t *testing.T, cache *helm.ChartCache) []string {
+				r1 := renderCachedChart(t, cache, newTestCacheChartFS("a"))
+				cache.Purge()
+				return []string{r1}
+			},
+			expRenders: []string{
+				"---\n# Source: test-chart/templates/cm.yaml\nname: a\n",
+			},
+			expLen: 0,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			cache, err := helm.NewChartCache(test.config)
+			require.NoError(t, err)
+
+			gotRenders := test.exec(t, cache)
+			assert.Equal(test.expRenders, gotRenders)
+			assert.Equal(test.expLen, cache.Len())
+		})
+	}
+}
+
+func TestChartCacheConcurrentRenders(t *testing.T) {
+	chartFS := newTestChartFS()
+	chartFS["Chart.yaml"] = &fstest.MapFile{Data: []byte("apiVersion: v2\nname: test-chart\nversion: 0.1.0\ndependencies:\n- name: sub1\n  version: 0.1.0\n  condition: sub1.enabled")}
+	chartFS["charts/sub1/Chart.yaml"] = &fstest.MapFile{Data: []byte("apiVersion: v2\nname: sub1\nversion: 0.1.0")}
+	chartFS["charts/sub1/templates/cm.yaml"] = &fstest.MapFile{Data: []byte("name: sub1")}
+
+	cache, err := helm.NewChartCache(helm.ChartCacheConfig{})
+	require.NoError(t, err)
+
+	// Rendering the shared chart with the subchart disabled should not affect the other renders.
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(enabled bool) {
+			defer wg.Done()
+
+			chart, err := helm.LoadChartWithConfig(context.TODO(), chartFS, helm.LoadChartConfig{Cache: cache})
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			out, err := helm.Template(context.TODO(), helm.TemplateConfig{
+				ReleaseName: "test",
+				Chart:       chart,
+				Values:      map[string]interface{}{"sub1": map[string]interface{}{"enabled": enabled}},
+			})
+			if assert.NoError(t, err) {
+				exp := ""
+				if enabled {
+					exp = "---\n# Source: test-chart/charts/sub1/templates/cm.yaml\nname: sub1\n"
+				}
+				assert.Equal(t, exp, out)
+			}
+		}(i%2 == 0)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, cache.Len())
+}
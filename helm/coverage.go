@@ -0,0 +1,356 @@
+package helm
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	texttemplate "text/template"
+	"text/template/parse"
+
+	"helm.sh/helm/v4/pkg/chart/common"
+	chartv2 "helm.sh/helm/v4/pkg/chart/v2"
+)
+
+const coverageFuncName = "goHelmTemplateCover"
+
+// TemplateCoverage collects the coverage of the chart templates across multiple renders
+// (e.g: all the chart unit tests), recording which template statements (actions, `if`, `range`
+// and `with` branches and text) have been executed.
+//
+// The templates are identified by their full name (e.g: `mychart/templates/deployment.yaml`). It's
+// safe to use it concurrently.
+type TemplateCoverage struct {
+	mu    sync.Mutex
+	files map[string]*templateFileCoverage
+}
+
+// NewTemplateCoverage returns a new empty template coverage collector.
+func NewTemplateCoverage() *TemplateCoverage {
+	return &TemplateCoverage{files: map[string]*templateFileCoverage{}}
+}
+
+// TemplateFileCoverage is the coverage of a chart template file.
+type TemplateFileCoverage struct {
+	// File is the template full name (e.g: `mychart/templates/deployment.yaml`).
+	File string
+	// Statements is the number of statements of the template.
+	Statements int
+	// Covered is the number of statements of the template that have been executed.
+	Covered int
+	// UncoveredLines are the template lines with statements that have not been executed.
+	UncoveredLines []int
+}
+
+// Percent returns the percentage of covered statements, a template without statements is
+// fully covered.
+func (t TemplateFileCoverage) Percent() float64 {
+	if t.Statements == 0 {
+		return 100
+	}
+
+	return float64(t.Covered) * 100 / float64(t.Statements)
+}
+
+// Files returns the coverage of all the rendered templates sorted by name.
+func (c *TemplateCoverage) Files() []TemplateFileCoverage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	files := []TemplateFileCoverage{}
+	for _, name := range c.sortedFiles() {
+		f := c.files[name]
+		fc := TemplateFileCoverage{File: name, Statements: len(f.statements), UncoveredLines: []int{}}
+		for _, s := range f.statements {
+			if f.counts[s.block] > 0 {
+				fc.Covered++
+				continue
+			}
+
+			line, _ := f.position(s.start)
+			if !slices.Contains(fc.UncoveredLines, line) {
+				fc.UncoveredLines = append(fc.UncoveredLines, line)
+			}
+		}
+		files = append(files, fc)
+	}
+
+	return files
+}
+
+// WriteText writes a text report with the coverage percentage of each template and the
+// uncovered lines.
+func (c *TemplateCoverage) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	statements, covered := 0, 0
+	for _, f := range c.Files() {
+		statements += f.Statements
+		covered += f.Covered
+
+		fmt.Fprintf(tw, "%s\t%.1f%%\t(%d/%d)", f.File, f.Percent(), f.Covered, f.Statements)
+		if len(f.UncoveredLines) > 0 {
+			lines := make([]string, 0, len(f.UncoveredLines))
+			for _, l := range f.UncoveredLines {
+				lines = append(lines, strconv.Itoa(l))
+			}
+			fmt.Fprintf(tw, "\tuncovered lines: %s", strings.Join(lines, ","))
+		}
+		fmt.Fprintln(tw)
+	}
+
+	total := TemplateFileCoverage{Statements: statements, Covered: covered}
+	fmt.Fprintf(tw, "total\t%.1f%%\t(%d/%d)\n", total.Percent(), total.Covered, total.Statements)
+
+	return tw.Flush()
+}
+
+// WriteProfile writes the coverage in the Go coverage profile format (`mode: count`), with a
+// block for each template statement.
+func (c *TemplateCoverage) WriteProfile(w io.Writer) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var b bytes.Buffer
+	b.WriteString("mode: count\n")
+	for _, name := range c.sortedFiles() {
+		f := c.files[name]
+		for _, s := range f.statements {
+			startLine, startCol := f.position(s.start)
+			endLine, endCol := f.position(s.end)
+			fmt.Fprintf(&b, "%s:%d.%d,%d.%d 1 %d\n", name, startLine, startCol, endLine, endCol, f.counts[s.block])
+		}
+	}
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+var coverageHTMLTpl = template.Must(template.New("coverage").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Helm templates coverage</title>
+<style>
+body { font-family: monospace; background: #fff; color: #333; }
+pre { border: 1px solid #ddd; padding: 8px; }
+.covered { background: #c8f0c8; }
+.uncovered { background: #f8c8c8; }
+</style>
+</head>
+<body>
+{{- range . }}
+<h2 id="{{ .File }}">{{ .File }} ({{ printf "%.1f" .Percent }}%)</h2>
+<pre>{{ range .Segments }}{{ if .Class }}<span class="{{ .Class }}">{{ .Text }}</span>{{ else }}{{ .Text }}{{ end }}{{ end }}</pre>
+{{- end }}
+</body>
+</html>
+`))
+
+type coverageHTMLFile struct {
+	File     string
+	Percent  float64
+	Segments []coverageHTMLSegment
+}
+
+type coverageHTMLSegment struct {
+	Text  string
+	Class string
+}
+
+// WriteHTML writes an HTML report with the source of each template highlighting the covered
+// and uncovered statements.
+func (c *TemplateCoverage) WriteHTML(w io.Writer) error {
+	files := c.Files()
+
+	c.mu.Lock()
+	htmlFiles := make([]coverageHTMLFile, 0, len(files))
+	for _, fc := range files {
+		f := c.files[fc.File]
+		hf := coverageHTMLFile{File: fc.File, Percent: fc.Percent()}
+
+		last := 0
+		for _, s := range f.statements {
+			if s.start < last {
+				continue
+			}
+			hf.Segments = append(hf.Segments, coverageHTMLSegment{Text: string(f.source[last:s.start])})
+
+			class := "uncovered"
+			if f.counts[s.block] > 0 {
+				class = "covered"
+			}
+			hf.Segments = append(hf.Segments, coverageHTMLSegment{Text: string(f.source[s.start:s.end]), Class: class})
+			last = s.end
+		}
+		hf.Segments = append(hf.Segments, coverageHTMLSegment{Text: string(f.source[last:])})
+		htmlFiles = append(htmlFiles, hf)
+	}
+	c.mu.Unlock()
+
+	return coverageHTMLTpl.Execute(w, htmlFiles)
+}
+
+func (c *TemplateCoverage) sortedFiles() []string {
+	names := make([]string, 0, len(c.files))
+	for n := range c.files {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// register registers a template file, the file coverage is kept if the template has already been
+// registered with the same source.
+func (c *TemplateCoverage) register(name string, f *templateFileCoverage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if current, ok := c.files[name]; ok && bytes.Equal(current.source, f.source) {
+		return
+	}
+	c.files[name] = f
+}
+
+func (c *TemplateCoverage) hit(name string, block int) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if f, ok := c.files[name]; ok && block < len(f.counts) {
+		f.counts[block]++
+	}
+
+	return ""
+}
+
+// templateFileCoverage is the coverage of a template, the statements are grouped in blocks (the
+// template lists executed together), the executions are counted per block.
+type templateFileCoverage struct {
+	source     []byte
+	actions    []sourceSpan
+	statements []coverageStatement
+	counts     []int
+}
+
+type coverageStatement struct {
+	// start and end are the source offsets of the statement.
+	start int
+	end   int
+	block int
+}
+
+func (f *templateFileCoverage) position(offset int) (line, col int) {
+	return sourcePosition(f.source, offset)
+}
+
+// instrumentBlock adds a block with the list statements, and instruments the list to count
+// the block executions.
+func (f *templateFileCoverage) instrumentBlock(name string, list *parse.ListNode) {
+	if list == nil {
+		return
+	}
+
+	block := len(f.counts)
+	f.counts = append(f.counts, 0)
+
+	for _, n := range list.Nodes {
+		pos := int(n.Position())
+		switch n := n.(type) {
+		case *parse.CommentNode:
+			continue
+		case *parse.ActionNode:
+			if isInstrumentationNode(n) {
+				continue
+			}
+		case *parse.TextNode:
+			text := string(n.Text)
+			trimmed := strings.TrimSpace(text)
+			if trimmed == "" {
+				continue
+			}
+			start := pos + strings.Index(text, trimmed)
+			f.statements = append(f.statements, coverageStatement{start: start, end: start + len(trimmed), block: block})
+			continue
+		}
+
+		// Actions and branches headers (e.g: `{{ if .Values.enabled }}`), the node position is
+		// inside the action.
+		action, ok := spanAt(f.actions, pos)
+		if !ok {
+			continue
+		}
+		f.statements = append(f.statements, coverageStatement{start: action.start, end: action.end, block: block})
+
+		switch n := n.(type) {
+		case *parse.IfNode:
+			f.instrumentBranch(name, &n.BranchNode)
+		case *parse.RangeNode:
+			f.instrumentBranch(name, &n.BranchNode)
+		case *parse.WithNode:
+			f.instrumentBranch(name, &n.BranchNode)
+		}
+	}
+
+	list.Nodes = append([]parse.Node{coverageFuncCallNode(name, block)}, list.Nodes...)
+}
+
+func (f *templateFileCoverage) instrumentBranch(name string, n *parse.BranchNode) {
+	f.instrumentBlock(name, n.List)
+	f.instrumentBlock(name, n.ElseList)
+}
+
+func coverageFuncCallNode(name string, block int) parse.Node {
+	trees, err := parseTemplate("cover", []byte(fmt.Sprintf("{{ %s %q %d }}", coverageFuncName, name, block)))
+	if err != nil {
+		// Should never happen, it's a generated template.
+		panic(err)
+	}
+
+	return trees["cover"].Root.Nodes[0]
+}
+
+// coverageInstrument is the render instrument that records the templates coverage.
+type coverageInstrument struct {
+	coverage *TemplateCoverage
+}
+
+func (c coverageInstrument) instrumentTemplate(fullName string, source []byte, trees map[string]*parse.Tree) error {
+	// Sort to have the same blocks on every render.
+	names := make([]string, 0, len(trees))
+	for n := range trees {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	f := &templateFileCoverage{source: source, actions: templateActions(source)}
+	for _, n := range names {
+		f.instrumentBlock(fullName, trees[n].Root)
+	}
+	slices.SortStableFunc(f.statements, func(a, b coverageStatement) int { return a.start - b.start })
+
+	c.coverage.register(fullName, f)
+
+	return nil
+}
+
+func (c coverageInstrument) funcs() texttemplate.FuncMap {
+	return texttemplate.FuncMap{coverageFuncName: c.coverage.hit}
+}
+
+func (c coverageInstrument) prepareValues(chart *chartv2.Chart, values common.Values) {}
+
+// preparedInstrument is a render instrument whose templates have already been prepared.
+type preparedInstrument struct {
+	renderInstrument
+}
+
+func (preparedInstrument) instrumentTemplate(fullName string, source []byte, trees map[string]*parse.Tree) error {
+	return nil
+}
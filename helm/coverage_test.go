@@ -0,0 +1,169 @@
+package helm_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/go-helm-template/helm"
+)
+
+func newTestCoverageChart() *helm.Chart {
+	chartFS := newTestChartFS()
+	chartFS["templates/cm.yaml"] = &fstest.MapFile{Data: []byte(`{{- if .Values.enabled }}
+enabled: true
+{{- else if .Values.other }}
+other: true
+{{- else }}
+enabled: false
+{{- end }}
+{{- range .Values.items }}
+item: {{ . }}
+{{- end }}
+{{ include "test.name" . }}
+`)}
+	chartFS["templates/_helpers.tpl"] = &fstest.MapFile{Data: []byte(`{{- define "test.name" -}}
+name: {{ .Release.Name }}
+{{- end }}
+{{- define "test.unused" -}}
+unused
+{{- end }}
+`)}
+
+	return mustLoadChart(chartFS)
+}
+
+func TestTemplateCoverage(t *testing.T) {
+	tests := map[string]struct {
+		render     func(t *testing.T, cov *helm.TemplateCoverage)
+		expFiles   []helm.TemplateFileCoverage
+		expProfile string
+		expText    string
+	}{
+		"Not rendering anything should not have coverage.": {
+			render:     func(t *testing.T, cov *helm.TemplateCoverage) {},
+			expFiles:   []helm.TemplateFileCoverage{},
+			expProfile: "mode: count\n",
+			expText:    "total  100.0%  (0/0)\n",
+		},
+
+		"Multiple renders should accumulate the coverage.": {
+			render: func(t *testing.T, cov *helm.TemplateCoverage) {
+				chart := newTestCoverageChart()
+				for _, values := range []map[string]interface{}{{"enabled": true}, {}} {
+					_, err := helm.Template(context.TODO(), helm.TemplateConfig{ReleaseName: "test", Chart: chart, Values: values, Coverage: cov})
+					require.NoError(t, err)
+				}
+			},
+			expFiles: []helm.TemplateFileCoverage{
+				{File: "test-chart/templates/_helpers.tpl", Statements: 3, Covered: 2, UncoveredLines: []int{5}},
+				{File: "test-chart/templates/cm.yaml", Statements: 9, Covered: 6, UncoveredLines: []int{4, 9}},
+			},
+			expProfile: `mode: count
+test-chart/templates/_helpers.tpl:2.1,2.6 1 2
+test-chart/templates/_helpers.tpl:2.7,2.26 1 2
+test-chart/templates/_helpers.tpl:5.1,5.7 1 0
+test-chart/templates/cm.yaml:1.1,1.26 1 2
+test-chart/templates/cm.yaml:2.1,2.14 1 1
+test-chart/templates/cm.yaml:3.1,3.29 1 1
+test-chart/templates/cm.yaml:4.1,4.12 1 0
+test-chart/templates/cm.yaml:6.1,6.15 1 1
+test-chart/templates/cm.yaml:8.1,8.27 1 2
+test-chart/templates/cm.yaml:9.1,9.6 1 0
+test-chart/templates/cm.yaml:9.7,9.14 1 0
+test-chart/templates/cm.yaml:11.1,11.28 1 2
+`,
+			expText: `test-chart/templates/_helpers.tpl  66.7%  (2/3)  uncovered lines: 5
+test-chart/templates/cm.yaml       66.7%  (6/9)  uncovered lines: 4,9
+total                              66.7%  (8/12)
+`,
+		},
+
+		"Templates with delimiters inside literals should record the statements correctly.": {
+			render: func(t *testing.T, cov *helm.TemplateCoverage) {
+				chartFS := newTestChartFS()
+				chartFS["templates/cm.yaml"] = &fstest.MapFile{Data: []byte(`a: '{{ "}}" }}'
+{{- if eq .Values.a "{{" }}
+b: '{{ printf "%s" ` + "`}}`" + ` }}'
+{{- end }}
+c: {{ '}' }}
+`)}
+				_, err := helm.Template(context.TODO(), helm.TemplateConfig{ReleaseName: "test", Chart: mustLoadChart(chartFS), Coverage: cov})
+				require.NoError(t, err)
+			},
+			expFiles: []helm.TemplateFileCoverage{
+				{File: "test-chart/templates/cm.yaml", Statements: 9, Covered: 6, UncoveredLines: []int{3}},
+			},
+			expProfile: `mode: count
+test-chart/templates/cm.yaml:1.1,1.5 1 1
+test-chart/templates/cm.yaml:1.5,1.15 1 1
+test-chart/templates/cm.yaml:1.15,1.16 1 1
+test-chart/templates/cm.yaml:2.1,2.28 1 1
+test-chart/templates/cm.yaml:3.1,3.5 1 0
+test-chart/templates/cm.yaml:3.5,3.27 1 0
+test-chart/templates/cm.yaml:3.27,3.28 1 0
+test-chart/templates/cm.yaml:5.1,5.3 1 1
+test-chart/templates/cm.yaml:5.4,5.13 1 1
+`,
+			expText: `test-chart/templates/cm.yaml  66.7%  (6/9)  uncovered lines: 3
+total                         66.7%  (6/9)
+`,
+		},
+
+		"Named templates rendering should record the coverage.": {
+			render: func(t *testing.T, cov *helm.TemplateCoverage) {
+				_, err := helm.RenderNamedTemplate(context.TODO(), newTestCoverageChart(), helm.TemplateConfig{ReleaseName: "test", Coverage: cov}, "test.unused")
+				require.NoError(t, err)
+			},
+			expFiles: []helm.TemplateFileCoverage{
+				{File: "test-chart/templates/_helpers.tpl", Statements: 3, Covered: 1, UncoveredLines: []int{2}},
+				{File: "test-chart/templates/cm.yaml", Statements: 9, Covered: 0, UncoveredLines: []int{1, 2, 3, 4, 6, 8, 9, 11}},
+			},
+			expProfile: `mode: count
+test-chart/templates/_helpers.tpl:2.1,2.6 1 0
+test-chart/templates/_helpers.tpl:2.7,2.26 1 0
+test-chart/templates/_helpers.tpl:5.1,5.7 1 1
+test-chart/templates/cm.yaml:1.1,1.26 1 0
+test-chart/templates/cm.yaml:2.1,2.14 1 0
+test-chart/templates/cm.yaml:3.1,3.29 1 0
+test-chart/templates/cm.yaml:4.1,4.12 1 0
+test-chart/templates/cm.yaml:6.1,6.15 1 0
+test-chart/templates/cm.yaml:8.1,8.27 1 0
+test-chart/templates/cm.yaml:9.1,9.6 1 0
+test-chart/templates/cm.yaml:9.7,9.14 1 0
+test-chart/templates/cm.yaml:11.1,11.28 1 0
+`,
+			expText: `test-chart/templates/_helpers.tpl  33.3%  (1/3)  uncovered lines: 2
+test-chart/templates/cm.yaml       0.0%   (0/9)  uncovered lines: 1,2,3,4,6,8,9,11
+total                              8.3%   (1/12)
+`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			cov := helm.NewTemplateCoverage()
+			test.render(t, cov)
+
+			assert.Equal(test.expFiles, cov.Files())
+
+			var profile bytes.Buffer
+			require.NoError(t, cov.WriteProfile(&profile))
+			assert.Equal(test.expProfile, profile.String())
+
+			var text bytes.Buffer
+			require.NoError(t, cov.WriteText(&text))
+			assert.Equal(test.expText, text.String())
+
+			var html bytes.Buffer
+			require.NoError(t, cov.WriteHTML(&html))
+			assert.Contains(html.String(), "<title>Helm templates coverage</title>")
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package helm
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"helm.sh/helm/v4/pkg/chart/common"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	kscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/yaml"
+)
+
+// DeprecatedAPI is a rendered resource that uses a deprecated or removed Kubernetes API.
+type DeprecatedAPI struct {
+	// Source is the template that rendered the resource (e.g: `mychart/templates/ingress.yaml`).
+	Source string
+	// APIVersion is the apiVersion of the resource.
+	APIVersion string
+	// Kind is the kind of the resource.
+	Kind string
+	// Namespace is the namespace of the resource.
+	Namespace string
+	// Name is the name of the resource.
+	Name string
+	// DeprecatedIn is the Kubernetes version where the API was deprecated (e.g: `v1.14`).
+	DeprecatedIn string
+	// RemovedIn is the Kubernetes version where the API is no longer served (e.g: `v1.22`),
+	// empty if the API removal is not planned.
+	RemovedIn string
+	// Removed is true when the API is no longer served on the target Kubernetes version.
+	Removed bool
+	// ReplacementAPIVersion is the apiVersion that should be used instead, empty if there is
+	// no replacement.
+	ReplacementAPIVersion string
+	// ReplacementKind is the kind that should be used instead, empty if there is no replacement.
+	ReplacementKind string
+}
+
+// FindDeprecatedAPIs analyzes the rendered manifests and returns the resources that use Kubernetes
+// APIs that are deprecated or removed on the target Kubernetes version (e.g: `1.25`, `v1.25.3`).
+//
+// If the Kubernetes version is empty, the one used to render the charts will be used.
+func FindDeprecatedAPIs(ctx context.Context, result *RenderResult, kubeVersion string) ([]DeprecatedAPI, error) {
+	if result == nil {
+		return nil, fmt.Errorf("render result is required")
+	}
+
+	kv := common.DefaultCapabilities.KubeVersion
+	if kubeVersion != "" {
+		v, err := common.ParseKubeVersion(kubeVersion)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Kubernetes version %q: %w", kubeVersion, err)
+		}
+		kv = *v
+	}
+
+	target, err := kubeVersionMajorMinor(kv)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Kubernetes version %q: %w", kv.Version, err)
+	}
+
+	deprecated := []DeprecatedAPI{}
+	for _, m := range splitRenderedManifests(result.Manifests) {
+		obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+		err := yaml.Unmarshal([]byte(m.content), &obj.Object)
+		if err != nil {
+			return nil, fmt.Errorf("could not unmarshal %q manifest: %w", m.source, err)
+		}
+
+		lifecycle, ok := lookupAPILifecycle(obj.GetAPIVersion(), obj.GetKind())
+		if !ok || !lifecycle.isDeprecatedOn(target) {
+			continue
+		}
+
+		d := DeprecatedAPI{
+			Source:       m.source,
+			APIVersion:   obj.GetAPIVersion(),
+			Kind:         obj.GetKind(),
+			Namespace:    obj.GetNamespace(),
+			Name:         obj.GetName(),
+			DeprecatedIn: formatKubeVersion(lifecycle.Deprecated),
+			Removed:      lifecycle.isRemovedOn(target),
+		}
+		if lifecycle.Removed != [2]int{} {
+			d.RemovedIn = formatKubeVersion(lifecycle.Removed)
+		}
+		if !lifecycle.Replacement.Empty() {
+			d.ReplacementAPIVersion = lifecycle.Replacement.GroupVersion().String()
+			d.ReplacementKind = lifecycle.Replacement.Kind
+		}
+		deprecated = append(deprecated, d)
+	}
+
+	return deprecated, nil
+}
+
+// apiLifecycle is the lifecycle of a Kubernetes API (apiVersion and kind).
+type apiLifecycle struct {
+	// Deprecated is the Kubernetes version (major, minor) where the API was deprecated.
+	Deprecated [2]int
+	// Removed is the Kubernetes version (major, minor) where the API is no longer served.
+	Removed [2]int
+	// Replacement is the API that should be used instead (if any).
+	Replacement schema.GroupVersionKind
+}
+
+// removedAPIs are the lifecycles of the APIs that are no longer available on the Kubernetes
+// client libraries.
+var removedAPIs = map[schema.GroupVersionKind]apiLifecycle{
+	{Group: "extensions", Version: "v1beta1", Kind: "PodSecurityPolicy"}: {
+		Deprecated:  [2]int{1, 11},
+		Removed:     [2]int{1, 16},
+		Replacement: schema.GroupVersionKind{Group: "policy", Version: "v1beta1", Kind: "PodSecurityPolicy"},
+	},
+	{Group: "policy", Version: "v1beta1", Kind: "PodSecurityPolicy"}: {
+		Deprecated: [2]int{1, 21},
+		Removed:    [2]int{1, 25},
+	},
+	{Group: "apiregistration.k8s.io", Version: "v1beta1", Kind: "APIService"}: {
+		Deprecated:  [2]int{1, 19},
+		Removed:     [2]int{1, 22},
+		Replacement: schema.GroupVersionKind{Group: "apiregistration.k8s.io", Version: "v1", Kind: "APIService"},
+	},
+}
+
+// lookupAPILifecycle returns the lifecycle of a Kubernetes builtin API, only the APIs that have
+// a deprecation will be returned.
+func lookupAPILifecycle(apiVersion, kind string) (*apiLifecycle, bool) {
+	gvk := schema.FromAPIVersionAndKind(apiVersion, kind)
+	if l, ok := removedAPIs[gvk]; ok {
+		return &l, true
+	}
+
+	obj, err := kscheme.Scheme.New(gvk)
+	if err != nil {
+		return nil, false
+	}
+
+	deprecated, ok := obj.(interface{ APILifecycleDeprecated() (int, int) })
+	if !ok {
+		return nil, false
+	}
+
+	l := &apiLifecycle{}
+	l.Deprecated[0], l.Deprecated[1] = deprecated.APILifecycleDeprecated()
+	if l.Deprecated == [2]int{} {
+		return nil, false
+	}
+
+	if removed, ok := obj.(interface{ APILifecycleRemoved() (int, int) }); ok {
+		l.Removed[0], l.Removed[1] = removed.APILifecycleRemoved()
+	}
+
+	if replacement, ok := obj.(interface {
+		APILifecycleReplacement() schema.GroupVersionKind
+	}); ok {
+		l.Replacement = replacement.APILifecycleReplacement()
+	}
+
+	return l, true
+}
+
+// isDeprecatedOn returns true if the API is deprecated on the Kubernetes version.
+func (a apiLifecycle) isDeprecatedOn(v [2]int) bool {
+	return compareKubeVersions(v, a.Deprecated) >= 0
+}
+
+// isRemovedOn returns true if the API is not served on the Kubernetes version.
+func (a apiLifecycle) isRemovedOn(v [2]int) bool {
+	return a.Removed != [2]int{} && compareKubeVersions(v, a.Removed) >= 0
+}
+
+func compareKubeVersions(a, b [2]int) int {
+	if a[0] != b[0] {
+		return a[0] - b[0]
+	}
+	return a[1] - b[1]
+}
+
+func formatKubeVersion(v [2]int) string {
+	return fmt.Sprintf("v%d.%d", v[0], v[1])
+}
+
+// kubeVersionMajorMinor returns the major and minor of a Kubernetes version.
+func kubeVersionMajorMinor(v common.KubeVersion) ([2]int, error) {
+	major, err := strconv.Atoi(v.Major)
+	if err != nil {
+		return [2]int{}, err
+	}
+
+	minor, err := strconv.Atoi(v.Minor)
+	if err != nil {
+		return [2]int{}, err
+	}
+
+	return [2]int{major, minor}, nil
+}
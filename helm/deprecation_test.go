@@ -0,0 +1,97 @@
+package helm_test
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/go-helm-template/helm"
+)
+
+func TestFindDeprecatedAPIs(t *testing.T) {
+	chartFS := newTestChartFS()
+	chartFS["templates/ingress.yaml"] = &fstest.MapFile{Data: []byte(`
+apiVersion: extensions/v1beta1
+kind: Ingress
+metadata:
+  name: test
+  namespace: {{ .Release.Namespace }}
+`)}
+	chartFS["templates/psp.yaml"] = &fstest.MapFile{Data: []byte(`
+apiVersion: policy/v1beta1
+kind: PodSecurityPolicy
+metadata:
+  name: test
+`)}
+	chartFS["templates/resources.yaml"] = &fstest.MapFile{Data: []byte(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: test
+---
+apiVersion: flowcontrol.apiserver.k8s.io/v1beta3
+kind: FlowSchema
+metadata:
+  name: test
+`)}
+
+	tests := map[string]struct {
+		kubeVersion string
+		expAPIs     []helm.DeprecatedAPI
+		expErr      bool
+	}{
+		"Old Kubernetes versions should only have the deprecated APIs of that version.": {
+			kubeVersion: "1.14",
+			expAPIs: []helm.DeprecatedAPI{
+				{Source: "test-chart/templates/ingress.yaml", APIVersion: "extensions/v1beta1", Kind: "Ingress", Namespace: "test-ns", Name: "test", DeprecatedIn: "v1.14", RemovedIn: "v1.22", ReplacementAPIVersion: "networking.k8s.io/v1", ReplacementKind: "Ingress"},
+			},
+		},
+
+		"Deprecated APIs should be marked as removed on the Kubernetes versions that don't serve them.": {
+			kubeVersion: "v1.25.3",
+			expAPIs: []helm.DeprecatedAPI{
+				{Source: "test-chart/templates/psp.yaml", APIVersion: "policy/v1beta1", Kind: "PodSecurityPolicy", Name: "test", DeprecatedIn: "v1.21", RemovedIn: "v1.25", Removed: true},
+				{Source: "test-chart/templates/ingress.yaml", APIVersion: "extensions/v1beta1", Kind: "Ingress", Namespace: "test-ns", Name: "test", DeprecatedIn: "v1.14", RemovedIn: "v1.22", Removed: true, ReplacementAPIVersion: "networking.k8s.io/v1", ReplacementKind: "Ingress"},
+			},
+		},
+
+		"Recent Kubernetes versions should have all the deprecated APIs.": {
+			kubeVersion: "1.32",
+			expAPIs: []helm.DeprecatedAPI{
+				{Source: "test-chart/templates/psp.yaml", APIVersion: "policy/v1beta1", Kind: "PodSecurityPolicy", Name: "test", DeprecatedIn: "v1.21", RemovedIn: "v1.25", Removed: true},
+				{Source: "test-chart/templates/ingress.yaml", APIVersion: "extensions/v1beta1", Kind: "Ingress", Namespace: "test-ns", Name: "test", DeprecatedIn: "v1.14", RemovedIn: "v1.22", Removed: true, ReplacementAPIVersion: "networking.k8s.io/v1", ReplacementKind: "Ingress"},
+				{Source: "test-chart/templates/resources.yaml", APIVersion: "flowcontrol.apiserver.k8s.io/v1beta3", Kind: "FlowSchema", Name: "test", DeprecatedIn: "v1.29", RemovedIn: "v1.32", Removed: true, ReplacementAPIVersion: "flowcontrol.apiserver.k8s.io/v1", ReplacementKind: "FlowSchema"},
+			},
+		},
+
+		"An invalid Kubernetes version should fail.": {
+			kubeVersion: "invalid",
+			expErr:      true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			result, err := helm.Render(context.TODO(), helm.TemplateConfig{
+				ReleaseName: "test",
+				Namespace:   "test-ns",
+				Chart:       mustLoadChart(chartFS),
+			})
+			if !assert.NoError(err) {
+				return
+			}
+
+			gotAPIs, err := helm.FindDeprecatedAPIs(context.TODO(), result, test.kubeVersion)
+
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expAPIs, gotAPIs)
+			}
+		})
+	}
+}
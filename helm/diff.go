@@ -0,0 +1,393 @@
+package helm
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// DiffChangeType is the type of a change between two renders.
+type DiffChangeType string
+
+const (
+	// DiffChangeTypeAdded is used when something is only on the new render.
+	DiffChangeTypeAdded DiffChangeType = "added"
+	// DiffChangeTypeRemoved is used when something is only on the old render.
+	DiffChangeTypeRemoved DiffChangeType = "removed"
+	// DiffChangeTypeChanged is used when something is on both renders but is different.
+	DiffChangeTypeChanged DiffChangeType = "changed"
+)
+
+// DiffConfig is the configuration of the renders diff.
+type DiffConfig struct {
+	// IgnoreFields are the resource field paths that will be ignored when comparing the resources.
+	// The paths are dot separated, the keys with special characters can be quoted with brackets,
+	// list items are selected by index, `*` matches any key or index and `**` matches any number
+	// of keys or indexes (including none).
+	// e.g: `metadata.labels["helm.sh/chart"]`, `spec.template.spec.containers[*].image`, `**.image`.
+	IgnoreFields []string
+}
+
+// DiffResult is the result of comparing two renders.
+type DiffResult struct {
+	// Resources are the resources that have been added, removed or changed, sorted by
+	// apiVersion, kind, namespace and name.
+	Resources []ResourceDiff
+}
+
+// HasChanges returns true if there is any resource change.
+func (d DiffResult) HasChanges() bool {
+	return len(d.Resources) > 0
+}
+
+// ResourceDiff is a resource change between two renders.
+type ResourceDiff struct {
+	// Type is the type of the change.
+	Type DiffChangeType
+	// APIVersion is the apiVersion of the resource.
+	APIVersion string
+	// Kind is the kind of the resource.
+	Kind string
+	// Namespace is the namespace of the resource.
+	Namespace string
+	// Name is the name of the resource.
+	Name string
+	// OldSource is the template that rendered the resource on the old render (empty if added).
+	OldSource string
+	// NewSource is the template that rendered the resource on the new render (empty if removed).
+	NewSource string
+	// Fields are the changed fields of the resource, only set on changed resources.
+	Fields []FieldDiff
+}
+
+// FieldDiff is a resource field change between two renders.
+type FieldDiff struct {
+	// Type is the type of the change.
+	Type DiffChangeType
+	// Path is the path of the field (e.g: `spec.template.spec.containers[0].image`).
+	Path string
+	// Old is the YAML of the old field value (empty if added).
+	Old string
+	// New is the YAML of the new field value (empty if removed).
+	New string
+}
+
+// Diff compares the resources of two renders, matching the resources by apiVersion, kind,
+// namespace and name, and returns the resources that have been added, removed or changed.
+//
+// A render with multiple resources with the same apiVersion, kind, namespace and name can't
+// be compared and will fail.
+func Diff(ctx context.Context, oldResult, newResult *RenderResult, config DiffConfig) (*DiffResult, error) {
+	if oldResult == nil || newResult == nil {
+		return nil, fmt.Errorf("old and new render results are required")
+	}
+
+	ignore := make([][]fieldPathSegment, 0, len(config.IgnoreFields))
+	for _, f := range config.IgnoreFields {
+		p, err := parseFieldPath(f)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ignore field %q: %w", f, err)
+		}
+		ignore = append(ignore, p)
+	}
+
+	oldResources, err := diffResources(oldResult.Manifests)
+	if err != nil {
+		return nil, fmt.Errorf("could not load old render resources: %w", err)
+	}
+
+	newResources, err := diffResources(newResult.Manifests)
+	if err != nil {
+		return nil, fmt.Errorf("could not load new render resources: %w", err)
+	}
+
+	keys := slices.Collect(maps.Keys(oldResources))
+	for k := range newResources {
+		if _, ok := oldResources[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	slices.SortFunc(keys, func(a, b diffResourceKey) int { return strings.Compare(a.String(), b.String()) })
+
+	result := &DiffResult{Resources: []ResourceDiff{}}
+	for _, k := range keys {
+		o, n := oldResources[k], newResources[k]
+		d := ResourceDiff{
+			APIVersion: k.apiVersion,
+			Kind:       k.kind,
+			Namespace:  k.namespace,
+			Name:       k.name,
+		}
+
+		switch {
+		case o == nil:
+			d.Type = DiffChangeTypeAdded
+			d.NewSource = n.source
+		case n == nil:
+			d.Type = DiffChangeTypeRemoved
+			d.OldSource = o.source
+		default:
+			fields := []FieldDiff{}
+			diffFields(nil, o.obj, n.obj, ignore, &fields)
+			if len(fields) == 0 {
+				continue
+			}
+			d.Type = DiffChangeTypeChanged
+			d.OldSource = o.source
+			d.NewSource = n.source
+			d.Fields = fields
+		}
+
+		result.Resources = append(result.Resources, d)
+	}
+
+	return result, nil
+}
+
+type diffResourceKey struct {
+	apiVersion string
+	kind       string
+	namespace  string
+	name       string
+}
+
+func (k diffResourceKey) String() string {
+	return strings.Join([]string{k.apiVersion, k.kind, k.namespace, k.name}, "/")
+}
+
+type diffResource struct {
+	source string
+	obj    map[string]interface{}
+}
+
+// diffResources returns the resources of the rendered manifests by their identity.
+func diffResources(manifests string) (map[diffResourceKey]*diffResource, error) {
+	resources := map[diffResourceKey]*diffResource{}
+	for _, m := range splitRenderedManifests(manifests) {
+		obj := map[string]interface{}{}
+		err := yaml.Unmarshal([]byte(m.content), &obj)
+		if err != nil {
+			return nil, fmt.Errorf("could not unmarshal %q manifest: %w", m.source, err)
+		}
+
+		// Empty documents (e.g: only comments).
+		if len(obj) == 0 {
+			continue
+		}
+
+		u := unstructured.Unstructured{Object: obj}
+		key := diffResourceKey{
+			apiVersion: u.GetAPIVersion(),
+			kind:       u.GetKind(),
+			namespace:  u.GetNamespace(),
+			name:       u.GetName(),
+		}
+		if r, ok := resources[key]; ok {
+			return nil, fmt.Errorf("duplicated resource %q on %q and %q manifests", key, r.source, m.source)
+		}
+		resources[key] = &diffResource{source: m.source, obj: obj}
+	}
+
+	return resources, nil
+}
+
+// diffFields compares recursively two resource field values adding the changes.
+func diffFields(path []fieldPathSegment, oldValue, newValue interface{}, ignore [][]fieldPathSegment, changes *[]FieldDiff) {
+	for _, i := range ignore {
+		if matchFieldPath(i, path) {
+			return
+		}
+	}
+
+	switch {
+	case oldValue == nil && newValue == nil:
+		return
+	case oldValue == nil:
+		*changes = append(*changes, FieldDiff{Type: DiffChangeTypeAdded, Path: formatFieldPath(path), New: fieldYAML(newValue)})
+		return
+	case newValue == nil:
+		*changes = append(*changes, FieldDiff{Type: DiffChangeTypeRemoved, Path: formatFieldPath(path), Old: fieldYAML(oldValue)})
+		return
+	}
+
+	oldMap, oldIsMap := oldValue.(map[string]interface{})
+	newMap, newIsMap := newValue.(map[string]interface{})
+	if oldIsMap && newIsMap {
+		keys := slices.Sorted(maps.Keys(oldMap))
+		for k := range newMap {
+			if _, ok := oldMap[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		slices.Sort(keys)
+
+		for _, k := range keys {
+			diffFields(append(slices.Clip(path), fieldPathSegment{key: k}), oldMap[k], newMap[k], ignore, changes)
+		}
+		return
+	}
+
+	oldList, oldIsList := oldValue.([]interface{})
+	newList, newIsList := newValue.([]interface{})
+	if oldIsList && newIsList {
+		for i := 0; i < max(len(oldList), len(newList)); i++ {
+			var o, n interface{}
+			if i < len(oldList) {
+				o = oldList[i]
+			}
+			if i < len(newList) {
+				n = newList[i]
+			}
+			diffFields(append(slices.Clip(path), fieldPathSegment{index: i, isIndex: true}), o, n, ignore, changes)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(oldValue, newValue) {
+		*changes = append(*changes, FieldDiff{Type: DiffChangeTypeChanged, Path: formatFieldPath(path), Old: fieldYAML(oldValue), New: fieldYAML(newValue)})
+	}
+}
+
+func fieldYAML(v interface{}) string {
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+
+	return strings.TrimSuffix(string(data), "\n")
+}
+
+// fieldPathSegment is a segment of a resource field path.
+type fieldPathSegment struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+	// recursive matches any number of segments.
+	recursive bool
+}
+
+var fieldPathSimpleKeyRe = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+func formatFieldPath(path []fieldPathSegment) string {
+	var b strings.Builder
+	for _, s := range path {
+		switch {
+		case s.isIndex:
+			fmt.Fprintf(&b, "[%d]", s.index)
+		case fieldPathSimpleKeyRe.MatchString(s.key):
+			if b.Len() > 0 {
+				b.WriteString(".")
+			}
+			b.WriteString(s.key)
+		default:
+			fmt.Fprintf(&b, "[%q]", s.key)
+		}
+	}
+
+	return b.String()
+}
+
+// parseFieldPath parses a field path (e.g: `metadata.labels["helm.sh/chart"]`, `spec.containers[*].image`).
+func parseFieldPath(p string) ([]fieldPathSegment, error) {
+	segments := []fieldPathSegment{}
+	for i := 0; i < len(p); {
+		switch {
+		case p[i] == '.':
+			if i == 0 || i == len(p)-1 || p[i+1] == '.' || p[i+1] == '[' {
+				return nil, fmt.Errorf("unexpected '.' at %d", i)
+			}
+			i++
+
+		case p[i] == '[':
+			end := strings.Index(p[i:], "]")
+			if strings.HasPrefix(p[i:], `["`) {
+				end = strings.Index(p[i:], `"]`) + 1
+			}
+			if end <= 0 {
+				return nil, fmt.Errorf("unclosed '[' at %d", i)
+			}
+
+			content := p[i+1 : i+end]
+			switch {
+			case content == "*":
+				segments = append(segments, fieldPathSegment{wildcard: true})
+			case strings.HasPrefix(content, `"`):
+				key, err := strconv.Unquote(content)
+				if err != nil {
+					return nil, fmt.Errorf("invalid quoted key at %d: %w", i, err)
+				}
+				segments = append(segments, fieldPathSegment{key: key})
+			default:
+				index, err := strconv.Atoi(content)
+				if err != nil || index < 0 {
+					return nil, fmt.Errorf("invalid index %q at %d", content, i)
+				}
+				segments = append(segments, fieldPathSegment{index: index, isIndex: true})
+			}
+			i += end + 1
+
+		default:
+			end := strings.IndexAny(p[i:], ".[")
+			if end < 0 {
+				end = len(p) - i
+			}
+
+			key := p[i : i+end]
+			switch key {
+			case "*":
+				segments = append(segments, fieldPathSegment{wildcard: true})
+			case "**":
+				segments = append(segments, fieldPathSegment{recursive: true})
+			default:
+				segments = append(segments, fieldPathSegment{key: key})
+			}
+			i += end
+		}
+	}
+
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("empty path")
+	}
+
+	return segments, nil
+}
+
+// matchFieldPath returns true if the field path matches the pattern path.
+func matchFieldPath(pattern, path []fieldPathSegment) bool {
+	for i, p := range pattern {
+		if p.recursive {
+			for j := i; j <= len(path); j++ {
+				if matchFieldPath(pattern[i+1:], path[j:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if i >= len(path) {
+			return false
+		}
+
+		s := path[i]
+		switch {
+		case p.wildcard:
+		case p.isIndex != s.isIndex:
+			return false
+		case p.isIndex && p.index != s.index:
+			return false
+		case !p.isIndex && p.key != s.key:
+			return false
+		}
+	}
+
+	return len(pattern) == len(path)
+}
@@ -0,0 +1,244 @@
+package helm_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/go-helm-template/helm"
+)
+
+func TestDiff(t *testing.T) {
+	tests := map[string]struct {
+		old       string
+		new       string
+		config    helm.DiffConfig
+		expResult *helm.DiffResult
+		expErr    bool
+	}{
+		"Same renders should not have changes.": {
+			old: `---
+# Source: test/templates/cm.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+data:
+  a: b
+`,
+			new: `---
+# Source: test/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+data:
+  a: b
+`,
+			expResult: &helm.DiffResult{Resources: []helm.ResourceDiff{}},
+		},
+
+		"Added and removed resources should be reported.": {
+			old: `---
+# Source: test/templates/cm.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+  namespace: ns1
+---
+# Source: test/templates/secret.yaml
+apiVersion: v1
+kind: Secret
+metadata:
+  name: test
+`,
+			new: `---
+# Source: test/templates/cm.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+  namespace: ns2
+---
+# Source: test/templates/secret.yaml
+apiVersion: v1
+kind: Secret
+metadata:
+  name: test
+`,
+			expResult: &helm.DiffResult{Resources: []helm.ResourceDiff{
+				{Type: helm.DiffChangeTypeRemoved, APIVersion: "v1", Kind: "ConfigMap", Namespace: "ns1", Name: "test", OldSource: "test/templates/cm.yaml"},
+				{Type: helm.DiffChangeTypeAdded, APIVersion: "v1", Kind: "ConfigMap", Namespace: "ns2", Name: "test", NewSource: "test/templates/cm.yaml"},
+			}},
+		},
+
+		"Changed resources should report the changed fields.": {
+			old: `---
+# Source: test/templates/deploy.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: test
+  labels:
+    app: test
+    helm.sh/chart: test-0.1.0
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app:v1
+`,
+			new: `---
+# Source: test/templates/deploy.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: test
+  labels:
+    helm.sh/chart: test-0.2.0
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app:v2
+      - name: sidecar
+        image: sidecar:v1
+`,
+			expResult: &helm.DiffResult{Resources: []helm.ResourceDiff{
+				{
+					Type:       helm.DiffChangeTypeChanged,
+					APIVersion: "apps/v1",
+					Kind:       "Deployment",
+					Name:       "test",
+					OldSource:  "test/templates/deploy.yaml",
+					NewSource:  "test/templates/deploy.yaml",
+					Fields: []helm.FieldDiff{
+						{Type: helm.DiffChangeTypeRemoved, Path: "metadata.labels.app", Old: "test"},
+						{Type: helm.DiffChangeTypeChanged, Path: `metadata.labels["helm.sh/chart"]`, Old: "test-0.1.0", New: "test-0.2.0"},
+						{Type: helm.DiffChangeTypeChanged, Path: "spec.replicas", Old: "1", New: "2"},
+						{Type: helm.DiffChangeTypeChanged, Path: "spec.template.spec.containers[0].image", Old: "app:v1", New: "app:v2"},
+						{Type: helm.DiffChangeTypeAdded, Path: "spec.template.spec.containers[1]", New: "image: sidecar:v1\nname: sidecar"},
+					},
+				},
+			}},
+		},
+
+		"Ignored fields should not be reported.": {
+			old: `---
+# Source: test/templates/deploy.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: test
+  labels:
+    helm.sh/chart: test-0.1.0
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app:v1
+`,
+			new: `---
+# Source: test/templates/deploy.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: test
+  labels:
+    helm.sh/chart: test-0.2.0
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app:v2
+`,
+			config: helm.DiffConfig{
+				IgnoreFields: []string{`metadata.labels["helm.sh/chart"]`, "spec.template.spec.containers[*].image"},
+			},
+			expResult: &helm.DiffResult{Resources: []helm.ResourceDiff{}},
+		},
+
+		"Recursive ignored fields should not be reported.": {
+			old: `---
+# Source: test/templates/deploy.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: test
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: init
+        image: init:v1
+      containers:
+      - name: app
+        image: app:v1
+`,
+			new: `---
+# Source: test/templates/deploy.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: test
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: init
+        image: init:v2
+      containers:
+      - name: app
+        image: app:v2
+`,
+			config: helm.DiffConfig{
+				IgnoreFields: []string{"spec.**.image"},
+			},
+			expResult: &helm.DiffResult{Resources: []helm.ResourceDiff{}},
+		},
+
+		"Duplicated resources should fail.": {
+			old: `---
+# Source: test/templates/cm1.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+---
+# Source: test/templates/cm2.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+`,
+			expErr: true,
+		},
+
+		"Invalid ignored fields should fail.": {
+			config: helm.DiffConfig{IgnoreFields: []string{"metadata..labels"}},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			gotResult, err := helm.Diff(context.TODO(), &helm.RenderResult{Manifests: test.old}, &helm.RenderResult{Manifests: test.new}, test.config)
+
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expResult, gotResult)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package helm
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"path"
+	"slices"
+	"strings"
+
+	"helm.sh/helm/v4/pkg/chart/common"
+	"helm.sh/helm/v4/pkg/chart/loader/archive"
+	chartv2 "helm.sh/helm/v4/pkg/chart/v2"
+	chartutilv2 "helm.sh/helm/v4/pkg/chart/v2/util"
+	"sigs.k8s.io/yaml"
+)
+
+// Digest returns the canonical SHA-256 digest of the chart files (e.g: `sha256:3f0a...`), the digest
+// is the same regardless of the files order or how the chart has been packaged (directory, archive or
+// `helm package`), so it can be used to identify the rendered chart (e.g: audit logs).
+//
+// The files are the ones Helm writes when packaging the chart: `Chart.yaml` and `Chart.lock` are
+// marshalled from the loaded metadata (so the formatting and comments are ignored), and the subcharts
+// are nested on the `charts` directory (even if they were archives).
+func (c *Chart) Digest() string {
+	return "sha256:" + chartDigest(c.v2)
+}
+
+// chartDigest returns the hex SHA-256 digest of the chart archive files.
+func chartDigest(chart *chartv2.Chart) string {
+	archiveFiles := chartArchiveFiles(chart, "")
+	files := make([]*archive.BufferedFile, 0, len(archiveFiles))
+	for _, f := range archiveFiles {
+		files = append(files, &archive.BufferedFile{Name: f.Name, Data: f.Data})
+	}
+
+	return filesDigest(files)
+}
+
+// chartArchiveFiles returns the chart files with the path prefix, the same way Helm writes them on
+// a chart archive (`chartutil.Save`).
+func chartArchiveFiles(chart *chartv2.Chart, prefix string) []*common.File {
+	// Helm doesn't save the v1 charts dependencies on `Chart.yaml`, they are on `requirements.yaml`.
+	metadata := *chart.Metadata
+	if metadata.APIVersion == chartv2.APIVersionV1 {
+		metadata.Dependencies = nil
+	}
+
+	// The metadata and the lock are plain structs, marshalling them can't fail.
+	data, _ := yaml.Marshal(metadata)
+	files := []*common.File{{Name: path.Join(prefix, chartutilv2.ChartfileName), Data: data}}
+
+	if metadata.APIVersion == chartv2.APIVersionV2 && chart.Lock != nil {
+		data, _ := yaml.Marshal(chart.Lock)
+		files = append(files, &common.File{Name: path.Join(prefix, "Chart.lock"), Data: data})
+	}
+
+	for _, f := range chart.Raw {
+		if f.Name == chartutilv2.ValuesfileName {
+			files = append(files, &common.File{Name: path.Join(prefix, f.Name), Data: f.Data})
+		}
+	}
+
+	if chart.Schema != nil {
+		files = append(files, &common.File{Name: path.Join(prefix, chartutilv2.SchemafileName), Data: chart.Schema})
+	}
+
+	for _, f := range slices.Concat(chart.Templates, chart.Files) {
+		files = append(files, &common.File{Name: path.Join(prefix, f.Name), Data: f.Data})
+	}
+
+	for _, dep := range chart.Dependencies() {
+		files = append(files, chartArchiveFiles(dep, path.Join(prefix, chartutilv2.ChartsDir, dep.Name()))...)
+	}
+
+	return files
+}
+
+// filesDigest returns the hex SHA-256 digest of the files, independent of the files order.
+func filesDigest(files []*archive.BufferedFile) string {
+	sorted := slices.Clone(files)
+	slices.SortFunc(sorted, func(a, b *archive.BufferedFile) int { return strings.Compare(a.Name, b.Name) })
+
+	h := sha256.New()
+	for _, f := range sorted {
+		// Length prefixed to avoid ambiguous names and data boundaries.
+		_ = binary.Write(h, binary.BigEndian, uint64(len(f.Name)))
+		h.Write([]byte(f.Name))
+		_ = binary.Write(h, binary.BigEndian, uint64(len(f.Data)))
+		h.Write(f.Data)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
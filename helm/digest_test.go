@@ -0,0 +1,136 @@
+package helm_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v4/pkg/chart/v2/loader"
+	chartutilv2 "helm.sh/helm/v4/pkg/chart/v2/util"
+
+	"github.com/slok/go-helm-template/helm"
+)
+
+type testArchiveFile struct {
+	name string
+	data string
+}
+
+// newTestChartArchive returns a chart archive with the files in the same order, inside a root directory.
+func newTestChartArchive(t *testing.T, dir string, files []testArchiveFile) []byte {
+	var b bytes.Buffer
+	gz := gzip.NewWriter(&b)
+	tw := tar.NewWriter(gz)
+	for _, f := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: dir + "/" + f.name, Mode: 0o644, Size: int64(len(f.data))}))
+		_, err := tw.Write([]byte(f.data))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+
+	return b.Bytes()
+}
+
+func TestChartDigest(t *testing.T) {
+	const (
+		chartYAML = "apiVersion: v2\nname: test-chart\nversion: 0.1.0"
+		cmYAML    = "name: {{ .Values.name }}"
+	)
+
+	loadArchive := func(t *testing.T, dir string, files []testArchiveFile) *helm.Chart {
+		chart, err := helm.LoadChartArchive(context.TODO(), bytes.NewReader(newTestChartArchive(t, dir, files)), helm.LoadChartArchiveConfig{})
+		require.NoError(t, err)
+		return chart
+	}
+
+	tests := map[string]struct {
+		chart     func(t *testing.T) *helm.Chart
+		expDigest string
+	}{
+		"A chart loaded from a FS should have the files digest.": {
+			chart: func(t *testing.T) *helm.Chart {
+				return mustLoadChart(fstest.MapFS{
+					"Chart.yaml":        &fstest.MapFile{Data: []byte(chartYAML)},
+					"templates/cm.yaml": &fstest.MapFile{Data: []byte(cmYAML)},
+				})
+			},
+			expDigest: "sha256:b12448565209955e16546169174ef68a2b298588faf5f8390bda2fbf16fc15f3",
+		},
+
+		"A chart loaded from an archive should have the same digest as the one loaded from a FS.": {
+			chart: func(t *testing.T) *helm.Chart {
+				return loadArchive(t, "test-chart", []testArchiveFile{{"Chart.yaml", chartYAML}, {"templates/cm.yaml", cmYAML}})
+			},
+			expDigest: "sha256:b12448565209955e16546169174ef68a2b298588faf5f8390bda2fbf16fc15f3",
+		},
+
+		"The digest should not depend on the archive files order or root directory.": {
+			chart: func(t *testing.T) *helm.Chart {
+				return loadArchive(t, "other", []testArchiveFile{{"templates/cm.yaml", cmYAML}, {"Chart.yaml", chartYAML}})
+			},
+			expDigest: "sha256:b12448565209955e16546169174ef68a2b298588faf5f8390bda2fbf16fc15f3",
+		},
+
+		"The digest should not depend on the Chart.yaml formatting.": {
+			chart: func(t *testing.T) *helm.Chart {
+				return loadArchive(t, "test-chart", []testArchiveFile{{"Chart.yaml", "# Test chart.\nversion: 0.1.0\nname: test-chart\napiVersion: v2\n"}, {"templates/cm.yaml", cmYAML}})
+			},
+			expDigest: "sha256:b12448565209955e16546169174ef68a2b298588faf5f8390bda2fbf16fc15f3",
+		},
+
+		"A chart with different content should have a different digest.": {
+			chart: func(t *testing.T) *helm.Chart {
+				return loadArchive(t, "test-chart", []testArchiveFile{{"Chart.yaml", chartYAML}, {"templates/cm.yaml", cmYAML + "\n"}})
+			},
+			expDigest: "sha256:14e818bfb5e454868d55343fe63cc810734de86a4b53f39df8302914a86fb68c",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expDigest, test.chart(t).Digest())
+		})
+	}
+}
+
+func TestChartDigestHelmPackage(t *testing.T) {
+	files := map[string]string{
+		"Chart.yaml":                    "# Test chart.\napiVersion: v2\nname: test-chart\nversion: 0.1.0\ndependencies:\n  - name: sub1\n    version: 0.1.0\n",
+		"Chart.lock":                    "dependencies:\n  - name: sub1\n    version: 0.1.0\n    repository: \"\"\ndigest: sha256:0000\ngenerated: \"2024-01-01T00:00:00Z\"\n",
+		"values.yaml":                   "name: test\n",
+		"README.md":                     "Test chart.",
+		"templates/cm.yaml":             "name: {{ .Values.name }}",
+		"charts/sub1/Chart.yaml":        "apiVersion: v2\nversion: 0.1.0\nname: sub1\n",
+		"charts/sub1/templates/cm.yaml": "sub: {{ .Values.sub }}",
+	}
+
+	dir := t.TempDir()
+	for name, data := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+		require.NoError(t, os.WriteFile(p, []byte(data), 0o644))
+	}
+
+	// Package the chart with Helm (like `helm package`).
+	helmChart, err := loader.LoadDir(dir)
+	require.NoError(t, err)
+	archivePath, err := chartutilv2.Save(helmChart, t.TempDir())
+	require.NoError(t, err)
+	archive, err := os.ReadFile(archivePath)
+	require.NoError(t, err)
+
+	dirChart, err := helm.LoadChart(context.TODO(), os.DirFS(dir))
+	require.NoError(t, err)
+	archiveChart, err := helm.LoadChartArchive(context.TODO(), bytes.NewReader(archive), helm.LoadChartArchiveConfig{})
+	require.NoError(t, err)
+
+	assert.Equal(t, dirChart.Digest(), archiveChart.Digest())
+}
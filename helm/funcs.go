@@ -0,0 +1,82 @@
+package helm
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+)
+
+// helmFuncNames are the functions that Helm engine adds to the Sprig ones, Helm doesn't export
+// its function map so these need to be kept in sync with the Helm version being used.
+var helmFuncNames = []string{
+	"toToml",
+	"fromToml",
+	"toYaml",
+	"mustToYaml",
+	"toYamlPretty",
+	"fromYaml",
+	"fromYamlArray",
+	"toJson",
+	"mustToJson",
+	"fromJson",
+	"fromJsonArray",
+	"include",
+	"tpl",
+	"required",
+	"fail",
+	"lookup",
+}
+
+// sprigRemovedFuncNames are the Sprig functions that Helm engine removes.
+var sprigRemovedFuncNames = []string{"env", "expandenv"}
+
+// builtinFuncNames returns the names of the functions that are available by default on Helm templates,
+// this includes Go text/template, Sprig and Helm functions.
+var builtinFuncNames = sync.OnceValue(func() map[string]struct{} {
+	names := map[string]struct{}{}
+
+	// Go text/template functions.
+	for _, name := range []string{"and", "call", "html", "index", "slice", "js", "len", "not", "or", "print", "printf", "println", "urlquery", "eq", "ge", "gt", "le", "lt", "ne"} {
+		names[name] = struct{}{}
+	}
+
+	// Sprig functions.
+	for name := range sprig.TxtFuncMap() {
+		if !slices.Contains(sprigRemovedFuncNames, name) {
+			names[name] = struct{}{}
+		}
+	}
+
+	// Helm functions.
+	for _, name := range helmFuncNames {
+		names[name] = struct{}{}
+	}
+
+	return names
+})
+
+// validateFuncs checks the custom template functions are correct, if override is not allowed
+// it will fail when a custom function has the same name as a builtin function.
+func validateFuncs(funcs template.FuncMap, allowOverride bool) error {
+	builtins := builtinFuncNames()
+	overridden := []string{}
+	for name, f := range funcs {
+		if f == nil {
+			return fmt.Errorf("template function %q is nil", name)
+		}
+
+		if _, ok := builtins[name]; ok && !allowOverride {
+			overridden = append(overridden, name)
+		}
+	}
+
+	if len(overridden) > 0 {
+		slices.Sort(overridden)
+		return fmt.Errorf("template functions %q override builtin functions and override is not allowed", overridden)
+	}
+
+	return nil
+}
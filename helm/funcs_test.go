@@ -0,0 +1,70 @@
+package helm_test
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+	"text/template"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/go-helm-template/helm"
+)
+
+func TestBuiltinFuncs(t *testing.T) {
+	tests := map[string]struct {
+		funcName string
+		builtin  bool
+	}{
+		"toToml should be a builtin function.":                          {funcName: "toToml", builtin: true},
+		"fromToml should be a builtin function.":                        {funcName: "fromToml", builtin: true},
+		"toYaml should be a builtin function.":                          {funcName: "toYaml", builtin: true},
+		"mustToYaml should be a builtin function.":                      {funcName: "mustToYaml", builtin: true},
+		"toYamlPretty should be a builtin function.":                    {funcName: "toYamlPretty", builtin: true},
+		"fromYaml should be a builtin function.":                        {funcName: "fromYaml", builtin: true},
+		"fromYamlArray should be a builtin function.":                   {funcName: "fromYamlArray", builtin: true},
+		"toJson should be a builtin function.":                          {funcName: "toJson", builtin: true},
+		"mustToJson should be a builtin function.":                      {funcName: "mustToJson", builtin: true},
+		"fromJson should be a builtin function.":                        {funcName: "fromJson", builtin: true},
+		"fromJsonArray should be a builtin function.":                   {funcName: "fromJsonArray", builtin: true},
+		"include should be a builtin function.":                         {funcName: "include", builtin: true},
+		"tpl should be a builtin function.":                             {funcName: "tpl", builtin: true},
+		"required should be a builtin function.":                        {funcName: "required", builtin: true},
+		"fail should be a builtin function.":                            {funcName: "fail", builtin: true},
+		"lookup should be a builtin function.":                          {funcName: "lookup", builtin: true},
+		"A Sprig function should be a builtin function.":                {funcName: "upper", builtin: true},
+		"A Go template function should be a builtin function.":          {funcName: "printf", builtin: true},
+		"env should not be a builtin function (removed by Helm).":       {funcName: "env"},
+		"expandenv should not be a builtin function (removed by Helm).": {funcName: "expandenv"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			chartFS := newTestChartFS()
+			chartFS["templates/cm.yaml"] = &fstest.MapFile{Data: []byte(`{{ if false }}{{ ` + test.funcName + ` }}{{ end }}`)}
+			chart := mustLoadChart(chartFS)
+
+			// The template is only parsed with the function if Helm has it.
+			_, err := helm.Template(context.TODO(), helm.TemplateConfig{ReleaseName: "test", Chart: chart})
+			if test.builtin {
+				assert.NoError(err)
+			} else {
+				assert.Error(err)
+			}
+
+			// Only the builtin functions can't be overridden.
+			_, err = helm.Template(context.TODO(), helm.TemplateConfig{
+				ReleaseName: "test",
+				Chart:       chart,
+				Funcs:       template.FuncMap{test.funcName: func() string { return "" }},
+			})
+			if test.builtin {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+		})
+	}
+}
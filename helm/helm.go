@@ -4,21 +4,48 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"io/fs"
+	"log/slog"
+	"maps"
+	"path"
 	"regexp"
+	"slices"
 	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
 
-	"helm.sh/helm/v4/pkg/action"
-	"helm.sh/helm/v4/pkg/chart"
+	"go.opentelemetry.io/otel/trace"
+	"helm.sh/helm/v4/pkg/chart/common"
+	chartutil "helm.sh/helm/v4/pkg/chart/common/util"
 	"helm.sh/helm/v4/pkg/chart/loader/archive"
 	chartv2 "helm.sh/helm/v4/pkg/chart/v2"
 	loaderv2 "helm.sh/helm/v4/pkg/chart/v2/loader"
-	"helm.sh/helm/v4/pkg/release"
+	chartutilv2 "helm.sh/helm/v4/pkg/chart/v2/util"
+	"helm.sh/helm/v4/pkg/engine"
+	"helm.sh/helm/v4/pkg/ignore"
+	releasev1 "helm.sh/helm/v4/pkg/release/v1"
+	releaseutilv1 "helm.sh/helm/v4/pkg/release/v1/util"
+)
+
+const (
+	defaultReleaseService  = "Helm"
+	installReleaseRevision = 1
+	upgradeReleaseRevision = 2
+	notesFileSuffix        = "NOTES.txt"
 )
 
 // Chart represents a loaded Helm chart.
 type Chart struct {
-	v2 *chartv2.Chart
+	v2         *chartv2.Chart
+	provenance *ProvenanceVerification
+}
+
+// Provenance returns the provenance verification of the chart, nil if the chart provenance
+// has not been verified.
+func (c *Chart) Provenance() *ProvenanceVerification {
+	return c.provenance
 }
 
 // TemplateConfig is the configuration for Helm Template rendering.
@@ -39,6 +66,54 @@ type TemplateConfig struct {
 	ShowFiles []string
 	// If enabled, hooks will be rendered, if disabled it will be ignored.
 	EnableHooks bool
+	// IsUpgrade when enabled will render the chart as a release upgrade instead of
+	// a release install (`.Release.IsUpgrade` instead of `.Release.IsInstall`).
+	IsUpgrade bool
+	// Revision is the release revision used on the rendering (`.Release.Revision`).
+	// By default 1 on installs and 2 on upgrades.
+	Revision int
+	// ReleaseService is the service that renders the release (`.Release.Service`).
+	// By default `Helm`.
+	ReleaseService string
+	// Funcs are custom template functions that will be available on the chart templates,
+	// these are added on top of the Sprig and Helm template functions.
+	Funcs template.FuncMap
+	// AllowFuncsOverride when enabled will let the custom template functions replace the builtin
+	// ones (Sprig, Helm...) with the same name, by default it will fail.
+	AllowFuncsOverride bool
+	// Sandbox when set will render the chart in a sandbox with restrictions (forbidden
+	// functions, output size...), useful to render untrusted charts.
+	Sandbox *SandboxConfig
+	// IncludeSubchartNotes when enabled will render the subcharts notes (`NOTES.txt`) along with
+	// the chart notes.
+	IncludeSubchartNotes bool
+	// Validation when set will validate the rendered manifests against the Kubernetes schemas,
+	// returning a `ValidationError` if any of the rendered resources is not valid.
+	Validation *ValidationConfig
+	// ValuesCoverage when enabled will record the values used while rendering and return the
+	// unused default and user values keys of the chart and subcharts (`RenderResult.ValuesCoverage`).
+	ValuesCoverage bool
+	// Coverage when set will record the executed template statements on the coverage collector,
+	// the same collector can be shared by multiple renders (e.g: chart unit tests).
+	Coverage *TemplateCoverage
+	// MissingValues is how the missing values referenced by the templates are handled, by default
+	// they are rendered as empty (`MissingValuesModeIgnore`).
+	MissingValues MissingValuesMode
+	// Logger when set will receive the debug events of each rendering stage, the render warnings
+	// (e.g: values with incompatible types, deprecated charts) and the Helm internal logs, instead
+	// of writing them to stderr.
+	//
+	// Helm logs with the process global loggers, so to redirect its logs, the `slog` default logger
+	// and the `log` package output are replaced while the Helm functions that log are called. The
+	// application logs are still sent to the original loggers, but the calls are serialized between
+	// renders. If missing, the global loggers are not touched.
+	Logger *slog.Logger
+	// TracerProvider when set will be used to trace the rendering stages with OpenTelemetry.
+	TracerProvider trace.TracerProvider
+	// Metrics when set will record the rendering metrics.
+	Metrics MetricsRecorder
+	// RenderCache when set will be used to reuse the results of the same renders.
+	RenderCache *RenderCache
 }
 
 func (c *TemplateConfig) defaults() error {
@@ -54,105 +129,787 @@ func (c *TemplateConfig) defaults() error {
 		c.Values = map[string]interface{}{}
 	}
 
+	if c.Revision < 0 {
+		return fmt.Errorf("revision can't be negative")
+	}
+
+	if c.Revision == 0 {
+		c.Revision = installReleaseRevision
+		if c.IsUpgrade {
+			c.Revision = upgradeReleaseRevision
+		}
+	}
+
+	if c.IsUpgrade && c.Revision < upgradeReleaseRevision {
+		return fmt.Errorf("upgrade revision must be greater than %d", installReleaseRevision)
+	}
+
+	if c.ReleaseService == "" {
+		c.ReleaseService = defaultReleaseService
+	}
+
+	err := validateFuncs(c.Funcs, c.AllowFuncsOverride)
+	if err != nil {
+		return fmt.Errorf("invalid template functions: %w", err)
+	}
+
+	err = c.MissingValues.validate()
+	if err != nil {
+		return fmt.Errorf("invalid missing values mode: %w", err)
+	}
+
+	if c.Validation != nil {
+		err := c.Validation.validate()
+		if err != nil {
+			return fmt.Errorf("invalid validation configuration: %w", err)
+		}
+	}
+
+	if c.Sandbox != nil {
+		sandbox := *c.Sandbox
+		err := sandbox.defaults()
+		if err != nil {
+			return fmt.Errorf("invalid sandbox configuration: %w", err)
+		}
+		c.Sandbox = &sandbox
+	}
+
 	return nil
 }
 
+// prepare validates and sets the defaults of the configuration, returning the chart that will be rendered.
+func (c *TemplateConfig) prepare() (*chartv2.Chart, error) {
+	err := c.defaults()
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	chart, err := c.Chart.chartV2()
+	if err != nil {
+		return nil, err
+	}
+
+	err = chartutilv2.ValidateReleaseName(c.ReleaseName)
+	if err != nil {
+		return nil, fmt.Errorf("invalid release name %q: %w", c.ReleaseName, err)
+	}
+
+	return chart, nil
+}
+
+// RenderResult is the result of rendering a chart.
+type RenderResult struct {
+	// Manifests are the rendered manifests, the same ones `Template` returns.
+	Manifests string
+	// Notes are the rendered chart notes (`NOTES.txt`).
+	Notes string
+	// ValuesCoverage is the values coverage report, only set when `TemplateConfig.ValuesCoverage`
+	// is enabled.
+	ValuesCoverage *ValuesCoverage
+	// MissingValues are the missing values referenced by the templates, only set when
+	// `TemplateConfig.MissingValues` is `MissingValuesModeWarn`.
+	MissingValues []MissingValue
+	// Warnings are the non fatal issues found while rendering the chart (e.g: values with
+	// incompatible types, deprecated charts).
+	Warnings []RenderWarning
+}
+
 // Template will runhelm template in the provided chart and values without the need of the Helm binary
 // and without executing an external command.
 func Template(ctx context.Context, config TemplateConfig) (string, error) {
-	err := config.defaults()
+	result, err := Render(ctx, config)
 	if err != nil {
-		return "", fmt.Errorf("invalid configuration: %w", err)
+		return "", err
 	}
 
-	// Create chart renderer.
-	client := action.NewInstall(action.NewConfiguration())
-	client.DryRunStrategy = action.DryRunClient
-	client.ReleaseName = config.ReleaseName
-	client.IncludeCRDs = config.IncludeCRDs
-	client.Namespace = config.Namespace
-	client.DisableHooks = true
+	return result.Manifests, nil
+}
 
-	// Render chart.
-	var chart chart.Charter
-	switch {
-	case config.Chart.v2 != nil:
-		chart = config.Chart.v2
-	default:
-		return "", fmt.Errorf("unsupported chart version")
-	}
+// Render is like `Template` but returns all the rendered data of the chart, not only the manifests
+// (e.g: NOTES.txt).
+func Render(ctx context.Context, config TemplateConfig) (result *RenderResult, err error) {
+	ctx, span := startSpan(ctx, config.TracerProvider, "Render")
+	defer func() { endSpan(span, err) }()
+
+	start := time.Now()
+	metrics := RenderMetrics{FailureReason: FailureReasonInvalidConfig}
+	defer func() {
+		metrics.Duration = time.Since(start)
+		if err != nil {
+			metrics.FailureReason = renderFailureReason(err, metrics.FailureReason)
+		} else {
+			metrics.FailureReason = ""
+			metrics.OutputBytes = len(result.Manifests)
+		}
+		metricsRecorderOrNoop(config.Metrics).ObserveRender(ctx, metrics)
+	}()
 
-	rel, err := client.Run(chart, config.Values)
+	chart, err := config.prepare()
 	if err != nil {
-		return "", fmt.Errorf("could not render helm chart correctly: %w", err)
+		return nil, err
 	}
+	metrics.Chart = chart.Name()
+	metrics.FailureReason = FailureReasonRender
+	span.SetAttributes(chartTraceAttrs(chart.Name(), chart.Metadata.Version)...)
+
+	logger := loggerOrDiscard(config.Logger)
+
+	// Cache errors don't fail the rendering, the chart is rendered like without cache.
+	cacheKey := ""
+	if config.RenderCache != nil {
+		key, cacheable, err := config.RenderCache.key(ctx, chart, config)
+		if err != nil {
+			logger.WarnContext(ctx, "could not prepare render cache", "error", err)
+		}
 
-	acc, err := release.NewAccessor(rel)
+		if cacheable {
+			cached, ok, err := config.RenderCache.get(ctx, key)
+			if err != nil {
+				logger.WarnContext(ctx, "could not get cached render", "error", err)
+			}
+			span.SetAttributes(traceAttrCached.Bool(ok))
+			if ok {
+				logger.DebugContext(ctx, "render loaded from cache", "key", key)
+				logRenderWarnings(ctx, logger, cached.Warnings)
+				return cached, nil
+			}
+			cacheKey = key
+		}
+	}
+
+	var instruments []renderInstrument
+	if config.Coverage != nil {
+		instruments = append(instruments, coverageInstrument{coverage: config.Coverage})
+	}
+
+	var tracker *valuesTracker
+	if config.ValuesCoverage {
+		tracker = newValuesTracker()
+		instruments = append(instruments, tracker)
+	}
+
+	var checker *valuesChecker
+	if config.MissingValues != MissingValuesModeIgnore {
+		checker = newValuesChecker(config.MissingValues)
+		instruments = append(instruments, checker)
+	}
+
+	rendered, err := render(ctx, chart, config, instruments...)
 	if err != nil {
-		return "", fmt.Errorf("could not access release data: %w", err)
+		if checker != nil {
+			err = checker.renderError(err)
+		}
+		return nil, fmt.Errorf("could not render helm chart correctly: %w", err)
 	}
 
-	manifests := acc.Manifest()
+	logRenderWarnings(ctx, logger, rendered.warnings)
+
+	manifests := rendered.manifests
 	if len(config.ShowFiles) > 0 {
+		_, filterSpan := startSpan(ctx, config.TracerProvider, "FilterFiles")
 		manifests, err = filterFiles(manifests, config.ShowFiles)
+		if err == nil {
+			filterSpan.SetAttributes(traceAttrManifests.Int(countManifests(manifests)))
+		}
+		endSpan(filterSpan, err)
 		if err != nil {
-			return "", fmt.Errorf("could not filter manifest files: %w", err)
+			return nil, fmt.Errorf("could not filter manifest files: %w", err)
 		}
+		logger.DebugContext(ctx, "manifests filtered", "files", config.ShowFiles)
 	}
 
-	hooks := acc.Hooks()
-	if config.EnableHooks && len(hooks) > 0 {
-		hookManifests, err := hooksToManifests(hooks)
+	if config.EnableHooks && len(rendered.hooks) > 0 {
+		_, hooksSpan := startSpan(ctx, config.TracerProvider, "AssembleHooks", traceAttrHooks.Int(len(rendered.hooks)))
+		manifests += hooksToManifests(rendered.hooks)
+		endSpan(hooksSpan, nil)
+		logger.DebugContext(ctx, "hooks added", "hooks", len(rendered.hooks))
+	}
+
+	if config.Validation != nil {
+		validator, err := newSchemaValidator(chart, *config.Validation)
 		if err != nil {
-			return "", fmt.Errorf("could not render hook manifests: %w", err)
+			return nil, fmt.Errorf("could not prepare manifests validation: %w", err)
+		}
+
+		failures := validator.validate(manifests)
+		if len(failures) > 0 {
+			return nil, fmt.Errorf("invalid manifests: %w", &ValidationError{Failures: failures})
 		}
-		manifests += hookManifests
+		logger.DebugContext(ctx, "manifests validated")
+	}
+
+	result = &RenderResult{
+		Manifests: manifests,
+		Notes:     rendered.notes,
+		Warnings:  rendered.warnings,
+	}
+
+	if tracker != nil {
+		result.ValuesCoverage = tracker.coverage(config.Values)
+	}
+
+	if config.MissingValues == MissingValuesModeWarn {
+		result.MissingValues = checker.missingValues()
+	}
+
+	if cacheKey != "" {
+		err := config.RenderCache.set(ctx, cacheKey, result)
+		if err != nil {
+			logger.WarnContext(ctx, "could not cache render", "error", err)
+		} else {
+			logger.DebugContext(ctx, "render cached", "key", cacheKey)
+		}
+	}
+
+	return result, nil
+}
+
+// logRenderWarnings logs the render warnings.
+func logRenderWarnings(ctx context.Context, logger *slog.Logger, warnings []RenderWarning) {
+	for _, w := range warnings {
+		logger.WarnContext(ctx, w.Message, "type", w.Type, "chart", w.Chart, "path", w.Path)
+	}
+}
+
+type renderedChart struct {
+	manifests string
+	hooks     []*releasev1.Hook
+	notes     string
+	warnings  []RenderWarning
+}
+
+// render renders the chart templates the same way Helm does on a client side dry-run install,
+// returning the manifests (CRDs included if required), the hooks and the notes.
+func render(ctx context.Context, chart *chartv2.Chart, config TemplateConfig, instruments ...renderInstrument) (*renderedChart, error) {
+	chart, files, warnings, err := renderFiles(ctx, chart, config, instruments...)
+	if err != nil {
+		return nil, err
+	}
+
+	// NOTES.txt are not resources, we don't want them as part of the manifests.
+	rootNotes := path.Join(chart.Name(), "templates", notesFileSuffix)
+	notes := map[string]string{}
+	for k, v := range files {
+		if !strings.HasSuffix(k, notesFileSuffix) {
+			continue
+		}
+
+		if k == rootNotes || config.IncludeSubchartNotes {
+			notes[k] = v
+		}
+		delete(files, k)
+	}
+
+	// Root chart notes first, and then the subcharts ones sorted.
+	notesNames := slices.Sorted(maps.Keys(notes))
+	slices.SortStableFunc(notesNames, func(a, b string) int {
+		switch {
+		case a == rootNotes:
+			return -1
+		case b == rootNotes:
+			return 1
+		default:
+			return 0
+		}
+	})
+	renderedNotes := make([]string, 0, len(notesNames))
+	for _, n := range notesNames {
+		renderedNotes = append(renderedNotes, notes[n])
+	}
+
+	var hooks []*releasev1.Hook
+	var sortedManifests []releaseutilv1.Manifest
+	withHelmLogs(config.Logger, func() {
+		hooks, sortedManifests, err = releaseutilv1.SortManifests(files, nil, releaseutilv1.InstallOrder)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not sort manifests: %w", err)
+	}
+	trace.SpanFromContext(ctx).SetAttributes(traceAttrManifests.Int(len(sortedManifests)), traceAttrHooks.Int(len(hooks)))
+	loggerOrDiscard(config.Logger).DebugContext(ctx, "manifests sorted", "manifests", len(sortedManifests), "hooks", len(hooks), "notes", len(renderedNotes))
+
+	var b bytes.Buffer
+	if config.IncludeCRDs {
+		for _, crd := range chart.CRDObjects() {
+			fmt.Fprintf(&b, "---\n# Source: %s\n%s\n", crd.Filename, string(crd.File.Data))
+		}
+	}
+	for _, m := range sortedManifests {
+		fmt.Fprintf(&b, "---\n# Source: %s\n%s\n", m.Name, m.Content)
+	}
+
+	return &renderedChart{
+		manifests: b.String(),
+		hooks:     hooks,
+		notes:     strings.Join(renderedNotes, "\n"),
+		warnings:  append(warnings, deprecatedChartWarnings(chart)...),
+	}, nil
+}
+
+// renderFiles renders all the chart templates returning the rendered files by template name and the values
+// warnings, it also returns the chart used for the rendering, this can be different from the original one
+// after processing the chart dependencies, sandbox...
+func renderFiles(ctx context.Context, chart *chartv2.Chart, config TemplateConfig, instruments ...renderInstrument) (*chartv2.Chart, map[string]string, []RenderWarning, error) {
+	logger := loggerOrDiscard(config.Logger).With("chart", chart.Name(), "version", chart.Metadata.Version)
+
+	caps := common.DefaultCapabilities.Copy()
+	if chart.Metadata.KubeVersion != "" && !chartutilv2.IsCompatibleRange(chart.Metadata.KubeVersion, caps.KubeVersion.String()) {
+		return nil, nil, nil, fmt.Errorf("chart requires kubeVersion: %s which is incompatible with Kubernetes %s", chart.Metadata.KubeVersion, caps.KubeVersion.Version)
+	}
+
+	funcs := template.FuncMap{}
+	maps.Copy(funcs, config.Funcs)
+
+	// Instrument the templates first, so they receive the original template sources.
+	if len(instruments) > 0 {
+		c, err := copyChart(chart, func(name string, data []byte) ([]byte, error) {
+			trees, err := parseTemplate(name, data)
+			if err != nil {
+				return nil, fmt.Errorf("could not parse template %q: %w", name, err)
+			}
+
+			for _, in := range instruments {
+				err := in.instrumentTemplate(name, data, trees)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			return treesToSource(name, data, trees), nil
+		})
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		chart = c
+
+		for _, in := range instruments {
+			maps.Copy(funcs, in.funcs())
+		}
+		logger.DebugContext(ctx, "templates instrumented", "instruments", len(instruments))
+	}
+
+	var sb *sandbox
+	if config.Sandbox != nil {
+		sb = newSandbox(*config.Sandbox)
+		c, err := copyChart(chart, sb.prepareTemplate)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		chart = c
+		maps.Copy(funcs, sb.funcs())
+		logger.DebugContext(ctx, "templates sandboxed")
+	}
+
+	// The dependencies processing mutates the chart, and the chart can be shared (e.g: cached charts).
+	chart, err := copyChart(chart, nil)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	_, valuesSpan := startSpan(ctx, config.TracerProvider, "CoalesceValues", chartTraceAttrs(chart.Name(), chart.Metadata.Version)...)
+	withHelmLogs(config.Logger, func() { err = chartutilv2.ProcessDependencies(chart, config.Values) })
+	if err != nil {
+		err = fmt.Errorf("chart dependencies processing failed: %w", err)
+		endSpan(valuesSpan, err)
+		return nil, nil, nil, err
+	}
+	logger.DebugContext(ctx, "dependencies processed", "dependencies", len(chart.Dependencies()))
+
+	values, warnings, err := renderValues(chart, config, caps)
+	valuesSpan.SetAttributes(traceAttrDependencies.Int(len(chart.Dependencies())))
+	endSpan(valuesSpan, err)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	logger.DebugContext(ctx, "values merged", "release", config.ReleaseName, "namespace", config.Namespace, "revision", config.Revision)
+
+	for _, in := range instruments {
+		in.prepareValues(chart, values)
+	}
+
+	_, execSpan := startSpan(ctx, config.TracerProvider, "ExecuteTemplates", chartTraceAttrs(chart.Name(), chart.Metadata.Version)...)
+	files, err := engine.Engine{CustomTemplateFuncs: funcs}.Render(chart, values)
+	execSpan.SetAttributes(traceAttrTemplates.Int(len(files)))
+	endSpan(execSpan, err)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	logger.DebugContext(ctx, "templates rendered", "templates", len(files))
+
+	return chart, files, warnings, nil
+}
+
+// renderInstrument instruments the chart rendering to analyze it (e.g: values coverage).
+type renderInstrument interface {
+	// instrumentTemplate mutates the parsed template (the template file and its named templates)
+	// before the rendering, all the instruments receive the original template source.
+	instrumentTemplate(fullName string, source []byte, trees map[string]*parse.Tree) error
+	// funcs returns the template functions required by the instrumented templates.
+	funcs() template.FuncMap
+	// prepareValues receives the chart and the values that will be rendered.
+	prepareValues(chart *chartv2.Chart, values common.Values)
+}
+
+// renderValues returns the values used on the chart rendering (`.Values`, `.Release`, `.Capabilities`...)
+// and the warnings of merging the user values with the chart default values.
+func renderValues(chart *chartv2.Chart, config TemplateConfig, caps *common.Capabilities) (common.Values, []RenderWarning, error) {
+	var values common.Values
+	var err error
+	withHelmLogs(config.Logger, func() {
+		values, err = chartutil.ToRenderValues(chart, config.Values, common.ReleaseOptions{
+			Name:      config.ReleaseName,
+			Namespace: config.Namespace,
+			Revision:  config.Revision,
+			IsInstall: !config.IsUpgrade,
+			IsUpgrade: config.IsUpgrade,
+		}, caps)
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not prepare render values: %w", err)
+	}
+
+	release, ok := values["Release"].(map[string]interface{})
+	if !ok {
+		return nil, nil, fmt.Errorf("unexpected release render values type")
+	}
+	release["Service"] = config.ReleaseService
+
+	return values, valuesWarnings(chart, config.Values), nil
+}
+
+func (c *Chart) chartV2() (*chartv2.Chart, error) {
+	switch {
+	case c.v2 != nil:
+		return c.v2, nil
+	default:
+		return nil, fmt.Errorf("unsupported chart version")
 	}
+}
 
-	return manifests, nil
+// LoadChartConfig is the configuration used to load a chart.
+type LoadChartConfig struct {
+	// Logger when set will receive the debug events of the chart loading and the Helm internal
+	// logs, instead of writing them to stderr.
+	//
+	// To redirect the Helm logs, the process global loggers are replaced while the chart is loaded,
+	// the same way as `TemplateConfig.Logger`. If missing, the global loggers are not touched.
+	Logger *slog.Logger
+	// TracerProvider when set will be used to trace the chart loading with OpenTelemetry.
+	TracerProvider trace.TracerProvider
+	// Metrics when set will record the chart loading metrics.
+	Metrics MetricsRecorder
+	// Cache when set will be used to reuse the already loaded charts, by default the chart files are
+	// still read to compute the cache key (see ChartCacheConfig.KeyByFS).
+	Cache *ChartCache
+	// DisableHelmignore disables ignoring the chart files matched by the chart `.helmignore` file and
+	// the Helm default rules (dot files in `templates/`).
+	DisableHelmignore bool
+	// IgnorePatterns are extra `.helmignore` patterns of the chart files that need to be ignored, these
+	// are applied even if `DisableHelmignore` is set.
+	IgnorePatterns []string
+	// Symlinks is how the chart symlinks are handled, by default the chart symlinks are skipped
+	// logging a warning (`SymlinksModeWarn`).
+	Symlinks SymlinksMode
+	// SymlinksRoot is the fs.FS where the symlinks are resolved when following them, the symlinks
+	// can't point outside of it, the chart fs.FS must be the `SymlinksRootChartDir` directory of it
+	// (e.g: a monorepo with charts sharing files).
+	// By default the chart fs.FS.
+	SymlinksRoot fs.FS
+	// SymlinksRootChartDir is the path of the chart directory on the `SymlinksRoot`.
+	SymlinksRootChartDir string
+}
+
+func (c *LoadChartConfig) defaults() error {
+	err := c.Symlinks.validate()
+	if err != nil {
+		return err
+	}
+
+	if c.SymlinksRoot != nil && !fs.ValidPath(c.SymlinksRootChartDir) {
+		return fmt.Errorf("invalid symlinks root chart directory %q", c.SymlinksRootChartDir)
+	}
+
+	return nil
 }
 
 // LoadChart loads a chart from a fs.FS system.
 // There chart files must be at the root of the provided fs.FS.
 // e.g: ./Chart.yaml, ./values.yaml ./templates/deployment.yaml...
 //
+// The files matched by the chart `.helmignore` are ignored, like `helm package` does.
+//
 // You can use `fs.Sub` as a helper tool to get the root chart.
 func LoadChart(ctx context.Context, f fs.FS) (*Chart, error) {
-	files := []*archive.BufferedFile{}
+	return LoadChartWithConfig(ctx, f, LoadChartConfig{})
+}
+
+// LoadChartWithConfig is the same as LoadChart but with a custom configuration.
+func LoadChartWithConfig(ctx context.Context, f fs.FS, config LoadChartConfig) (chart *Chart, err error) {
+	ctx, span := startSpan(ctx, config.TracerProvider, "LoadChart")
+	defer func() { endSpan(span, err) }()
 
-	err := fs.WalkDir(f, ".", func(path string, d fs.DirEntry, err error) error {
+	start := time.Now()
+	defer func() { observeChartLoad(ctx, config.Metrics, start, chart, err) }()
+
+	err = config.defaults()
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	logger := loggerOrDiscard(config.Logger)
+
+	if config.Cache != nil {
+		c, cached, err := config.Cache.load(ctx, f, newReadChartFilesOptions(config), logger, loadChartFiles)
 		if err != nil {
-			return err
+			return nil, err
 		}
+		span.SetAttributes(chartTraceAttrs(c.Name(), c.Metadata.Version)...)
+		span.SetAttributes(traceAttrCached.Bool(cached))
+		logger.DebugContext(ctx, "chart loaded", "chart", c.Name(), "version", c.Metadata.Version, "dependencies", len(c.Dependencies()), "cached", cached)
+
+		return &Chart{v2: c}, nil
+	}
+
+	files, err := readChartFiles(ctx, f, newReadChartFilesOptions(config), logger)
+	if err != nil {
+		return nil, err
+	}
+	logger.DebugContext(ctx, "chart files read", "files", len(files))
 
-		if d.IsDir() || d.Type() == fs.ModeSymlink {
-			return nil
+	c, err := loadChartFiles(files, logger)
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttributes(chartTraceAttrs(c.Name(), c.Metadata.Version)...)
+	span.SetAttributes(traceAttrFiles.Int(len(files)), traceAttrDependencies.Int(len(c.Dependencies())))
+	logger.DebugContext(ctx, "chart loaded", "chart", c.Name(), "version", c.Metadata.Version, "dependencies", len(c.Dependencies()))
+
+	return &Chart{v2: c}, nil
+}
+
+// LoadChartArchiveConfig is the configuration used to load a chart archive.
+type LoadChartArchiveConfig struct {
+	// Logger when set will receive the debug events of the chart loading and the Helm internal
+	// logs, instead of writing them to stderr (see `LoadChartConfig.Logger`).
+	Logger *slog.Logger
+	// TracerProvider when set will be used to trace the chart loading with OpenTelemetry.
+	TracerProvider trace.TracerProvider
+	// Metrics when set will record the chart loading metrics.
+	Metrics MetricsRecorder
+	// Provenance when set will verify the chart archive with its provenance file, the chart
+	// loading fails if the verification fails.
+	Provenance *ProvenanceConfig
+}
+
+func (c *LoadChartArchiveConfig) defaults() error {
+	if c.Provenance != nil {
+		err := c.Provenance.defaults()
+		if err != nil {
+			return fmt.Errorf("invalid provenance: %w", err)
 		}
+	}
+
+	return nil
+}
+
+// LoadChartArchive loads a chart from a packaged chart archive (`.tgz`), like the ones
+// created with `helm package`.
+func LoadChartArchive(ctx context.Context, r io.Reader, config LoadChartArchiveConfig) (chart *Chart, err error) {
+	ctx, span := startSpan(ctx, config.TracerProvider, "LoadChartArchive")
+	defer func() { endSpan(span, err) }()
+
+	start := time.Now()
+	defer func() { observeChartLoad(ctx, config.Metrics, start, chart, err) }()
+
+	err = config.defaults()
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	logger := loggerOrDiscard(config.Logger)
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("could not read chart archive: %w", err)
+	}
+
+	return loadChartArchive(ctx, data, config.Provenance, logger)
+}
 
-		data, err := fs.ReadFile(f, path)
+// loadChartArchive loads a chart from the chart archive data, verifying its provenance if required.
+func loadChartArchive(ctx context.Context, data []byte, prov *ProvenanceConfig, logger *slog.Logger) (*Chart, error) {
+	files, err := archive.LoadArchiveFiles(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("could not read chart archive files: %w", err)
+	}
+	logger.DebugContext(ctx, "chart files read", "files", len(files))
+
+	c, err := loadChartFiles(files, logger)
+	if err != nil {
+		return nil, err
+	}
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(chartTraceAttrs(c.Name(), c.Metadata.Version)...)
+	span.SetAttributes(traceAttrFiles.Int(len(files)), traceAttrDependencies.Int(len(c.Dependencies())))
+	logger.DebugContext(ctx, "chart loaded", "chart", c.Name(), "version", c.Metadata.Version, "dependencies", len(c.Dependencies()))
+
+	chart := &Chart{v2: c}
+	if prov != nil {
+		archiveName := prov.ArchiveName
+		if archiveName == "" {
+			archiveName = fmt.Sprintf("%s-%s.tgz", c.Name(), c.Metadata.Version)
+		}
+
+		chart.provenance, err = verifyProvenance(data, archiveName, *prov)
 		if err != nil {
-			return fmt.Errorf("could not read manifest %s: %w", path, err)
+			return nil, fmt.Errorf("could not verify chart provenance: %w", err)
 		}
+		logger.DebugContext(ctx, "chart provenance verified", "signed-by", chart.provenance.SignedBy, "hash", chart.provenance.FileHash)
+	}
 
-		files = append(files, &archive.BufferedFile{
-			Name: path,
-			Data: data,
-		})
+	return chart, nil
+}
 
-		return nil
-	})
+// observeChartLoad records the metrics of a chart load.
+func observeChartLoad(ctx context.Context, recorder MetricsRecorder, start time.Time, chart *Chart, err error) {
+	metrics := ChartLoadMetrics{Duration: time.Since(start)}
+	if err != nil {
+		metrics.FailureReason = FailureReasonLoad
+	} else {
+		metrics.Chart = chart.v2.Name()
+	}
+	metricsRecorderOrNoop(recorder).ObserveChartLoad(ctx, metrics)
+}
+
+// readChartFiles reads all the chart files of the fs.FS, except the ignored ones.
+func readChartFiles(ctx context.Context, f fs.FS, opts readChartFilesOptions, logger *slog.Logger) ([]*archive.BufferedFile, error) {
+	rules, err := chartIgnoreRules(f, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &chartFilesReader{
+		ctx:    ctx,
+		opts:   opts,
+		rules:  rules,
+		logger: logger,
+		root:   f,
+	}
+
+	// The symlinks can point outside of the chart, so the files are read from the symlinks root.
+	rootChartDir := "."
+	if opts.symlinks == SymlinksModeFollow && opts.symlinksRoot != nil {
+		r.root = opts.symlinksRoot
+		rootChartDir = path.Clean(opts.symlinksRootChartDir)
+	}
+
+	err = r.readDir(rootChartDir, ".", []string{rootChartDir})
 	if err != nil {
 		return nil, fmt.Errorf("could not walk chart directory: %w", err)
 	}
 
+	return r.files, nil
+}
+
+// chartFilesReader reads the chart files recursively following the symlinks if required.
+type chartFilesReader struct {
+	ctx    context.Context
+	opts   readChartFilesOptions
+	rules  *ignore.Rules
+	logger *slog.Logger
+	root   fs.FS
+	files  []*archive.BufferedFile
+}
+
+// readDir reads the files of a root directory as the files of a chart directory, the walked
+// directories are the root directories being read, used to detect the symlinks loops.
+func (r *chartFilesReader) readDir(dir, chartDir string, walked []string) error {
+	entries, err := fs.ReadDir(r.root, dir)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		rootPath := path.Join(dir, e.Name())
+		chartPath := path.Join(chartDir, e.Name())
+
+		info, err := e.Info()
+		if err != nil {
+			return err
+		}
+
+		if r.rules.Ignore(chartPath, info) {
+			continue
+		}
+
+		if e.Type() == fs.ModeSymlink {
+			switch r.opts.symlinks {
+			case SymlinksModeFollow:
+			case SymlinksModeError:
+				return fmt.Errorf("chart file %s is a symlink, symlinks are only loaded in follow mode", chartPath)
+			default:
+				r.logger.WarnContext(r.ctx, "chart symlink skipped", "path", chartPath)
+				continue
+			}
+
+			rootPath, err = resolveSymlink(r.root, rootPath)
+			if err != nil {
+				return err
+			}
+
+			info, err = fs.Stat(r.root, rootPath)
+			if err != nil {
+				return err
+			}
+
+			// Ignore using the target, the rules can be only for directories.
+			if r.rules.Ignore(chartPath, info) {
+				continue
+			}
+
+			if info.IsDir() {
+				for _, w := range walked {
+					if isSubPath(w, rootPath) {
+						return fmt.Errorf("chart symlink %s points to one of its parent directories (symlinks loop)", chartPath)
+					}
+				}
+			}
+		}
+
+		if info.IsDir() {
+			err := r.readDir(rootPath, chartPath, append(walked, rootPath))
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		data, err := fs.ReadFile(r.root, rootPath)
+		if err != nil {
+			return fmt.Errorf("could not read manifest %s: %w", chartPath, err)
+		}
+
+		r.files = append(r.files, &archive.BufferedFile{
+			Name: chartPath,
+			Data: data,
+		})
+	}
+
+	return nil
+}
+
+// loadChartFiles loads a chart from its files, the Helm logs are sent to the logger.
+func loadChartFiles(files []*archive.BufferedFile, logger *slog.Logger) (*chartv2.Chart, error) {
 	// TODO(slok): When we have v3 chart support (https://github.com/helm/helm/blob/main/internal/chart/v3/chart.go)
 	// we should select the proper loader.
-	chartv2, err := loaderv2.LoadFiles(files)
+	var c *chartv2.Chart
+	var err error
+	withHelmLogs(logger, func() { c, err = loaderv2.LoadFiles(files) })
 	if err != nil {
 		return nil, fmt.Errorf("could not load chart from files: %w", err)
 	}
 
-	return &Chart{v2: chartv2}, nil
+	return c, nil
 }
 
 // MustLoadChart is the same as LoadChart but panics if there is
@@ -171,6 +928,11 @@ var (
 	chartRenderedFileNameRe = regexp.MustCompile(`(?m)^# Source:(.*)$`)
 )
 
+// countManifests returns the number of manifests (YAML documents) of the rendered manifests.
+func countManifests(rendered string) int {
+	return len(chartRenderedFileNameRe.FindAllStringIndex(rendered, -1))
+}
+
 func filterFiles(rendered string, files []string) (string, error) {
 	renderedSplit := splitMarkRe.Split(rendered, -1)
 	// Create an index to check if we need to filter (and a counter to see if we filtered something related with the file).
@@ -219,15 +981,36 @@ func filterFiles(rendered string, files []string) (string, error) {
 	return result, nil
 }
 
-func hooksToManifests(hooks []release.Hook) (string, error) {
+// renderedManifest is a YAML document of the rendered manifests.
+type renderedManifest struct {
+	// source is the template that rendered the document.
+	source  string
+	content string
+}
+
+// splitRenderedManifests splits the rendered manifests in YAML documents, ignoring the empty ones.
+func splitRenderedManifests(manifests string) []renderedManifest {
+	docs := []renderedManifest{}
+	for _, doc := range splitMarkRe.Split(manifests, -1) {
+		if strings.TrimSpace(doc) == "" {
+			continue
+		}
+
+		source := ""
+		if match := chartRenderedFileNameRe.FindStringSubmatch(doc); len(match) > 0 {
+			source = strings.TrimSpace(match[1])
+		}
+		docs = append(docs, renderedManifest{source: source, content: doc})
+	}
+
+	return docs
+}
+
+func hooksToManifests(hooks []*releasev1.Hook) string {
 	var manifests bytes.Buffer
 	for _, h := range hooks {
-		hacc, err := release.NewHookAccessor(h)
-		if err != nil {
-			return "", fmt.Errorf("could not access hook data: %w", err)
-		}
-		fmt.Fprintf(&manifests, "---\n# Source: %s\n%s\n", hacc.Path(), hacc.Manifest())
+		fmt.Fprintf(&manifests, "---\n# Source: %s\n%s\n", h.Path, h.Manifest)
 	}
 
-	return strings.TrimSpace(manifests.String()), nil
+	return strings.TrimSpace(manifests.String())
 }
@@ -3,8 +3,10 @@ package helm_test
 import (
 	"context"
 	"io/fs"
+	"strings"
 	"testing"
 	"testing/fstest"
+	"text/template"
 
 	"github.com/stretchr/testify/assert"
 
@@ -166,6 +168,142 @@ func TestTemplate(t *testing.T) {
 			expManifests: "---\n# Source: test-chart/templates/something.yaml\nsomething: something\n---\n# Source: test-chart/templates/myhook.yaml\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: test\n  annotations:\n    helm.sh/hook: pre-install",
 		},
 
+		"By default, the chart should be rendered as an install.": {
+			chart: func() *helm.Chart {
+				chartFS := newTestChartFS()
+				chartFS["templates/something.yaml"] = &fstest.MapFile{Data: []byte("install: {{ .Release.IsInstall }}\nupgrade: {{ .Release.IsUpgrade }}\nrevision: {{ .Release.Revision }}\nservice: {{ .Release.Service }}")}
+				c := mustLoadChart(chartFS)
+				return c
+			},
+			config:       helm.TemplateConfig{ReleaseName: "test"},
+			expManifests: "---\n# Source: test-chart/templates/something.yaml\ninstall: true\nupgrade: false\nrevision: 1\nservice: Helm\n",
+		},
+
+		"Rendering as an upgrade should set the release as an upgrade.": {
+			chart: func() *helm.Chart {
+				chartFS := newTestChartFS()
+				chartFS["templates/something.yaml"] = &fstest.MapFile{Data: []byte("install: {{ .Release.IsInstall }}\nupgrade: {{ .Release.IsUpgrade }}\nrevision: {{ .Release.Revision }}\nservice: {{ .Release.Service }}")}
+				c := mustLoadChart(chartFS)
+				return c
+			},
+			config: helm.TemplateConfig{
+				ReleaseName: "test",
+				IsUpgrade:   true,
+			},
+			expManifests: "---\n# Source: test-chart/templates/something.yaml\ninstall: false\nupgrade: true\nrevision: 2\nservice: Helm\n",
+		},
+
+		"Rendering as an upgrade with custom revision and service should set them on the release.": {
+			chart: func() *helm.Chart {
+				chartFS := newTestChartFS()
+				chartFS["templates/something.yaml"] = &fstest.MapFile{Data: []byte("install: {{ .Release.IsInstall }}\nupgrade: {{ .Release.IsUpgrade }}\nrevision: {{ .Release.Revision }}\nservice: {{ .Release.Service }}")}
+				c := mustLoadChart(chartFS)
+				return c
+			},
+			config: helm.TemplateConfig{
+				ReleaseName:    "test",
+				IsUpgrade:      true,
+				Revision:       42,
+				ReleaseService: "go-helm-template",
+			},
+			expManifests: "---\n# Source: test-chart/templates/something.yaml\ninstall: false\nupgrade: true\nrevision: 42\nservice: go-helm-template\n",
+		},
+
+		"Rendering as an upgrade with the install revision should fail.": {
+			chart: func() *helm.Chart {
+				chartFS := newTestChartFS()
+				c := mustLoadChart(chartFS)
+				return c
+			},
+			config: helm.TemplateConfig{
+				ReleaseName: "test",
+				IsUpgrade:   true,
+				Revision:    1,
+			},
+			expErr: true,
+		},
+
+		"Having a chart with upgrade hooks and rendering as an upgrade, it should return the upgrade hooks.": {
+			chart: func() *helm.Chart {
+				chartFS := newTestChartFS()
+				chartFS["templates/something.yaml"] = &fstest.MapFile{Data: []byte(`something: something`)}
+				chartFS["templates/myhook.yaml"] = &fstest.MapFile{Data: []byte("{{- if .Release.IsUpgrade }}\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: test\n  annotations:\n    helm.sh/hook: pre-upgrade\n{{- end }}")}
+				c := mustLoadChart(chartFS)
+				return c
+			},
+			config: helm.TemplateConfig{
+				ReleaseName: "test",
+				EnableHooks: true,
+				IsUpgrade:   true,
+			},
+			expManifests: "---\n# Source: test-chart/templates/something.yaml\nsomething: something\n---\n# Source: test-chart/templates/myhook.yaml\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: test\n  annotations:\n    helm.sh/hook: pre-upgrade",
+		},
+
+		"Having custom template functions, the templates should be able to use them.": {
+			chart: func() *helm.Chart {
+				chartFS := newTestChartFS()
+				chartFS["templates/something.yaml"] = &fstest.MapFile{Data: []byte(`something: {{ .Release.Name | shout }}`)}
+				c := mustLoadChart(chartFS)
+				return c
+			},
+			config: helm.TemplateConfig{
+				ReleaseName: "test",
+				Funcs: template.FuncMap{
+					"shout": func(s string) string { return strings.ToUpper(s) + "!" },
+				},
+			},
+			expManifests: "---\n# Source: test-chart/templates/something.yaml\nsomething: TEST!\n",
+		},
+
+		"Having custom template functions that override builtin functions without allowing it, it should fail.": {
+			chart: func() *helm.Chart {
+				chartFS := newTestChartFS()
+				chartFS["templates/something.yaml"] = &fstest.MapFile{Data: []byte(`something: {{ .Release.Name | upper }}`)}
+				c := mustLoadChart(chartFS)
+				return c
+			},
+			config: helm.TemplateConfig{
+				ReleaseName: "test",
+				Funcs: template.FuncMap{
+					"upper": func(s string) string { return "overridden" },
+				},
+			},
+			expErr: true,
+		},
+
+		"Having custom template functions that override Helm functions without allowing it, it should fail.": {
+			chart: func() *helm.Chart {
+				chartFS := newTestChartFS()
+				chartFS["templates/something.yaml"] = &fstest.MapFile{Data: []byte(`something: {{ .Release.Name | toYaml }}`)}
+				c := mustLoadChart(chartFS)
+				return c
+			},
+			config: helm.TemplateConfig{
+				ReleaseName: "test",
+				Funcs: template.FuncMap{
+					"toYaml": func(v any) string { return "overridden" },
+				},
+			},
+			expErr: true,
+		},
+
+		"Having custom template functions that override builtin functions allowing it, it should use the custom ones.": {
+			chart: func() *helm.Chart {
+				chartFS := newTestChartFS()
+				chartFS["templates/something.yaml"] = &fstest.MapFile{Data: []byte(`something: {{ .Release.Name | upper }}`)}
+				c := mustLoadChart(chartFS)
+				return c
+			},
+			config: helm.TemplateConfig{
+				ReleaseName: "test",
+				Funcs: template.FuncMap{
+					"upper": func(s string) string { return "overridden" },
+				},
+				AllowFuncsOverride: true,
+			},
+			expManifests: "---\n# Source: test-chart/templates/something.yaml\nsomething: overridden\n",
+		},
+
 		"Filtering files should only return the files specified.": {
 			chart: func() *helm.Chart {
 				chartFS := newTestChartFS()
@@ -289,3 +427,84 @@ func TestLoadChart(t *testing.T) {
 		})
 	}
 }
+
+func TestRender(t *testing.T) {
+	tests := map[string]struct {
+		chart     func() *helm.Chart
+		config    helm.TemplateConfig
+		expResult *helm.RenderResult
+		expErr    bool
+	}{
+		"A chart without notes should not return notes.": {
+			chart: func() *helm.Chart {
+				chartFS := newTestChartFS()
+				chartFS["templates/something.yaml"] = &fstest.MapFile{Data: []byte(`something: something`)}
+				return mustLoadChart(chartFS)
+			},
+			config: helm.TemplateConfig{ReleaseName: "test"},
+			expResult: &helm.RenderResult{
+				Manifests: "---\n# Source: test-chart/templates/something.yaml\nsomething: something\n",
+			},
+		},
+
+		"A chart with notes should return the rendered notes.": {
+			chart: func() *helm.Chart {
+				chartFS := newTestChartFS()
+				chartFS["templates/something.yaml"] = &fstest.MapFile{Data: []byte(`something: something`)}
+				chartFS["templates/NOTES.txt"] = &fstest.MapFile{Data: []byte(`{{ .Release.Name }} has been installed on {{ .Release.Namespace }}.`)}
+				return mustLoadChart(chartFS)
+			},
+			config: helm.TemplateConfig{ReleaseName: "test", Namespace: "test-ns"},
+			expResult: &helm.RenderResult{
+				Manifests: "---\n# Source: test-chart/templates/something.yaml\nsomething: something\n",
+				Notes:     "test has been installed on test-ns.",
+			},
+		},
+
+		"A chart with subchart notes should only return the chart notes by default.": {
+			chart: func() *helm.Chart {
+				chartFS := newTestChartFS()
+				chartFS["templates/NOTES.txt"] = &fstest.MapFile{Data: []byte(`Chart {{ .Chart.Name }}.`)}
+				chartFS["charts/sub1/Chart.yaml"] = &fstest.MapFile{Data: []byte("apiVersion: v2\nname: sub1\nversion: 0.1.0")}
+				chartFS["charts/sub1/templates/NOTES.txt"] = &fstest.MapFile{Data: []byte(`Subchart {{ .Chart.Name }}.`)}
+				return mustLoadChart(chartFS)
+			},
+			config: helm.TemplateConfig{ReleaseName: "test"},
+			expResult: &helm.RenderResult{
+				Notes: "Chart test-chart.",
+			},
+		},
+
+		"A chart with subchart notes should return all the notes if enabled.": {
+			chart: func() *helm.Chart {
+				chartFS := newTestChartFS()
+				chartFS["templates/NOTES.txt"] = &fstest.MapFile{Data: []byte(`Chart {{ .Chart.Name }}.`)}
+				chartFS["charts/sub1/Chart.yaml"] = &fstest.MapFile{Data: []byte("apiVersion: v2\nname: sub1\nversion: 0.1.0")}
+				chartFS["charts/sub1/templates/NOTES.txt"] = &fstest.MapFile{Data: []byte(`Subchart {{ .Chart.Name }}.`)}
+				chartFS["charts/sub2/Chart.yaml"] = &fstest.MapFile{Data: []byte("apiVersion: v2\nname: sub2\nversion: 0.1.0")}
+				chartFS["charts/sub2/templates/NOTES.txt"] = &fstest.MapFile{Data: []byte(`Subchart {{ .Chart.Name }}.`)}
+				return mustLoadChart(chartFS)
+			},
+			config: helm.TemplateConfig{ReleaseName: "test", IncludeSubchartNotes: true},
+			expResult: &helm.RenderResult{
+				Notes: "Chart test-chart.\nSubchart sub1.\nSubchart sub2.",
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			config := test.config
+			config.Chart = test.chart()
+			gotResult, err := helm.Render(context.TODO(), config)
+
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expResult, gotResult)
+			}
+		})
+	}
+}
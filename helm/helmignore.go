@@ -0,0 +1,55 @@
+package helm
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+
+	"helm.sh/helm/v4/pkg/ignore"
+)
+
+// readChartFilesOptions are the options used to read the chart files of a fs.FS, it's comparable so
+// it can be part of the cache keys.
+type readChartFilesOptions struct {
+	disableHelmignore bool
+	// ignorePatterns are the extra ignore patterns separated by new lines.
+	ignorePatterns       string
+	symlinks             SymlinksMode
+	symlinksRoot         fs.FS
+	symlinksRootChartDir string
+}
+
+func newReadChartFilesOptions(config LoadChartConfig) readChartFilesOptions {
+	return readChartFilesOptions{
+		disableHelmignore:    config.DisableHelmignore,
+		ignorePatterns:       strings.Join(config.IgnorePatterns, "\n"),
+		symlinks:             config.Symlinks,
+		symlinksRoot:         config.SymlinksRoot,
+		symlinksRootChartDir: config.SymlinksRootChartDir,
+	}
+}
+
+// chartIgnoreRules returns the rules of the chart files that need to be ignored, the same way
+// `helm package` does: the chart `.helmignore` rules and the Helm default rules.
+func chartIgnoreRules(f fs.FS, opts readChartFilesOptions) (*ignore.Rules, error) {
+	rules := opts.ignorePatterns
+	if !opts.disableHelmignore {
+		data, err := fs.ReadFile(f, ignore.HelmIgnore)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("could not read %s: %w", ignore.HelmIgnore, err)
+		}
+		rules = string(data) + "\n" + rules
+	}
+
+	r, err := ignore.Parse(strings.NewReader(rules))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse ignore rules: %w", err)
+	}
+
+	if !opts.disableHelmignore {
+		r.AddDefaults()
+	}
+
+	return r, nil
+}
@@ -0,0 +1,93 @@
+package helm_test
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/go-helm-template/helm"
+)
+
+func TestLoadChartHelmignore(t *testing.T) {
+	newChartFS := func(helmignore string) fstest.MapFS {
+		chartFS := newTestChartFS()
+		chartFS["templates/files.yaml"] = &fstest.MapFile{Data: []byte("files:\n{{- range $path, $_ := .Files }}\n- {{ $path }}\n{{- end }}")}
+		chartFS["templates/.cm.yaml"] = &fstest.MapFile{Data: []byte("name: hidden")}
+		chartFS["README.md"] = &fstest.MapFile{Data: []byte("readme")}
+		chartFS["README.md~"] = &fstest.MapFile{Data: []byte("backup")}
+		chartFS["tests/test.yaml"] = &fstest.MapFile{Data: []byte("test")}
+		chartFS["files/a.bin"] = &fstest.MapFile{Data: []byte("a")}
+		if helmignore != "" {
+			chartFS[".helmignore"] = &fstest.MapFile{Data: []byte(helmignore)}
+		}
+
+		return chartFS
+	}
+
+	tests := map[string]struct {
+		fs     fstest.MapFS
+		config helm.LoadChartConfig
+		expOut string
+		expErr bool
+	}{
+		"A chart without .helmignore should ignore the Helm default ignored files.": {
+			fs:     newChartFS(""),
+			expOut: "---\n# Source: test-chart/templates/files.yaml\nfiles:\n- README.md\n- README.md~\n- files/a.bin\n- tests/test.yaml\n",
+		},
+
+		"A chart with .helmignore should ignore the matched files and directories.": {
+			fs:     newChartFS("# Comment.\n*~\ntests/\n*.bin\n"),
+			expOut: "---\n# Source: test-chart/templates/files.yaml\nfiles:\n- .helmignore\n- README.md\n",
+		},
+
+		"A chart with .helmignore path patterns should ignore the matched files.": {
+			fs:     newChartFS("files/*.bin\n"),
+			expOut: "---\n# Source: test-chart/templates/files.yaml\nfiles:\n- .helmignore\n- README.md\n- README.md~\n- tests/test.yaml\n",
+		},
+
+		"Extra ignore patterns should be applied along the .helmignore ones.": {
+			fs:     newChartFS("tests/\n"),
+			config: helm.LoadChartConfig{IgnorePatterns: []string{"*~", "files/"}},
+			expOut: "---\n# Source: test-chart/templates/files.yaml\nfiles:\n- .helmignore\n- README.md\n",
+		},
+
+		"Disabling .helmignore should load all the files.": {
+			fs:     newChartFS("*~\ntests/\n*.bin\n"),
+			config: helm.LoadChartConfig{DisableHelmignore: true},
+			expOut: "---\n# Source: test-chart/templates/.cm.yaml\nname: hidden\n---\n# Source: test-chart/templates/files.yaml\nfiles:\n- .helmignore\n- README.md\n- README.md~\n- files/a.bin\n- tests/test.yaml\n",
+		},
+
+		"Disabling .helmignore should still apply the extra ignore patterns.": {
+			fs:     newChartFS("*~\n"),
+			config: helm.LoadChartConfig{DisableHelmignore: true, IgnorePatterns: []string{"tests/", "templates/.*", ".helmignore"}},
+			expOut: "---\n# Source: test-chart/templates/files.yaml\nfiles:\n- README.md\n- README.md~\n- files/a.bin\n",
+		},
+
+		"Invalid ignore patterns should fail.": {
+			fs:     newChartFS(""),
+			config: helm.LoadChartConfig{IgnorePatterns: []string{"**/a"}},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			chart, err := helm.LoadChartWithConfig(context.TODO(), test.fs, test.config)
+			if test.expErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+
+			out, err := helm.Template(context.TODO(), helm.TemplateConfig{ReleaseName: "test", Chart: chart})
+			require.NoError(err)
+			assert.Equal(test.expOut, out)
+		})
+	}
+}
@@ -0,0 +1,399 @@
+package helm
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"helm.sh/helm/v4/pkg/chart/common"
+	chartv2 "helm.sh/helm/v4/pkg/chart/v2"
+	chartutilv2 "helm.sh/helm/v4/pkg/chart/v2/util"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	lintReleaseName          = "test-release"
+	lintRequiredFuncName     = "required"
+	lintRequiredFuncIDPrefix = "goHelmTemplateLintRequired"
+)
+
+// LintSeverity is the severity of a lint finding.
+type LintSeverity string
+
+const (
+	// LintSeverityInfo is used for recommendations.
+	LintSeverityInfo LintSeverity = "INFO"
+	// LintSeverityWarning is used for problems that don't break the chart.
+	LintSeverityWarning LintSeverity = "WARNING"
+	// LintSeverityError is used for problems that break the chart.
+	LintSeverityError LintSeverity = "ERROR"
+)
+
+// LintRule is the rule that found a lint finding.
+type LintRule string
+
+const (
+	// LintRuleChartfile checks the chart metadata (Chart.yaml).
+	LintRuleChartfile LintRule = "chartfile"
+	// LintRuleTemplate checks the templates can be parsed and rendered.
+	LintRuleTemplate LintRule = "template"
+	// LintRuleRequiredValue checks the values required by the templates (`required`) are set.
+	LintRuleRequiredValue LintRule = "required-value"
+	// LintRuleManifest checks the rendered manifests are valid Kubernetes YAML resources.
+	LintRuleManifest LintRule = "manifest"
+	// LintRuleDeprecatedAPI checks the rendered manifests don't use deprecated Kubernetes APIs.
+	LintRuleDeprecatedAPI LintRule = "deprecated-api"
+)
+
+// LintFinding is a problem found while linting a chart.
+type LintFinding struct {
+	// Severity is the severity of the problem.
+	Severity LintSeverity
+	// Rule is the rule that found the problem (e.g: `LintRuleTemplate`).
+	Rule LintRule
+	// File is the chart file path where the problem was found (e.g: `templates/deployment.yaml`,
+	// `charts/sub/templates/deployment.yaml`), empty if the problem is not related with a file.
+	File string
+	// Line is the line of the file where the problem was found, 0 if unknown.
+	// On the rendered manifests checks, the line is the one of the rendered template.
+	Line int
+	// Message is the description of the problem.
+	Message string
+}
+
+func (f LintFinding) String() string {
+	location := f.File
+	if location != "" && f.Line > 0 {
+		location += ":" + strconv.Itoa(f.Line)
+	}
+	if location != "" {
+		location += ": "
+	}
+
+	return fmt.Sprintf("[%s] %s%s (%s)", f.Severity, location, f.Message, f.Rule)
+}
+
+// LintResult is the result of linting a chart.
+type LintResult struct {
+	// Findings are the problems found, sorted by file and line.
+	Findings []LintFinding
+}
+
+// HasErrors returns true if any of the findings is an error.
+func (r LintResult) HasErrors() bool {
+	for _, f := range r.Findings {
+		if f.Severity == LintSeverityError {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Lint checks a chart for possible issues, like `helm lint` does, rendering the chart with the
+// provided values.
+//
+// Lint only returns an error when the chart can't be linted, the problems of the chart are
+// returned as findings.
+func Lint(ctx context.Context, chart *Chart, values map[string]interface{}) (*LintResult, error) {
+	config := TemplateConfig{
+		Chart:       chart,
+		ReleaseName: lintReleaseName,
+		Values:      values,
+	}
+	c, err := config.prepare()
+	if err != nil {
+		return nil, err
+	}
+
+	l := &linter{}
+	l.lintChartfile(c)
+	l.lintTemplateSources(c)
+
+	// Only render charts that can be rendered.
+	if !l.result().HasErrors() {
+		l.lintRender(ctx, c, config)
+	}
+
+	return l.result(), nil
+}
+
+type linter struct {
+	findings []LintFinding
+}
+
+func (l *linter) add(severity LintSeverity, rule LintRule, file string, line int, msg string) {
+	l.findings = append(l.findings, LintFinding{
+		Severity: severity,
+		Rule:     rule,
+		File:     file,
+		Line:     line,
+		Message:  msg,
+	})
+}
+
+func (l *linter) result() *LintResult {
+	findings := slices.Clone(l.findings)
+	slices.SortStableFunc(findings, func(a, b LintFinding) int {
+		if a.File != b.File {
+			return strings.Compare(a.File, b.File)
+		}
+		return a.Line - b.Line
+	})
+
+	return &LintResult{Findings: findings}
+}
+
+func (l *linter) lintChartfile(chart *chartv2.Chart) {
+	const file = "Chart.yaml"
+
+	if chart.Metadata == nil {
+		l.add(LintSeverityError, LintRuleChartfile, file, 0, "chart metadata is missing")
+		return
+	}
+
+	err := chart.Metadata.Validate()
+	if err != nil {
+		l.add(LintSeverityError, LintRuleChartfile, file, 0, err.Error())
+	}
+
+	if chart.Metadata.Icon == "" {
+		l.add(LintSeverityInfo, LintRuleChartfile, file, 0, "icon is recommended")
+	}
+
+	if chart.Metadata.Deprecated {
+		l.add(LintSeverityWarning, LintRuleChartfile, file, 0, "chart is deprecated")
+	}
+
+	kubeVersion := common.DefaultCapabilities.KubeVersion
+	if chart.Metadata.KubeVersion != "" && !chartutilv2.IsCompatibleRange(chart.Metadata.KubeVersion, kubeVersion.String()) {
+		l.add(LintSeverityError, LintRuleChartfile, file, 0, fmt.Sprintf("chart requires kubeVersion %s which is incompatible with Kubernetes %s", chart.Metadata.KubeVersion, kubeVersion.Version))
+	}
+
+	subcharts := map[string]bool{}
+	for _, dep := range chart.Dependencies() {
+		subcharts[dep.Name()] = true
+	}
+	for _, dep := range chart.Metadata.Dependencies {
+		if dep == nil || subcharts[dep.Name] {
+			continue
+		}
+		l.add(LintSeverityError, LintRuleChartfile, file, 0, fmt.Sprintf("chart dependency %q is missing on charts directory", dep.Name))
+	}
+}
+
+var templateErrorLocationRe = regexp.MustCompile(`^template: [^:]+:(\d+):(?:\d+:)? *(.*)$`)
+
+func (l *linter) lintTemplateSources(chart *chartv2.Chart) {
+	for _, t := range chartTemplates(chart) {
+		_, err := parseTemplate(t.Name, t.File.Data)
+		if err == nil {
+			continue
+		}
+
+		line, msg := 0, err.Error()
+		if match := templateErrorLocationRe.FindStringSubmatch(msg); match != nil {
+			line, _ = strconv.Atoi(match[1])
+			msg = match[2]
+		}
+		l.add(LintSeverityError, LintRuleTemplate, chartRelativePath(t.Name), line, fmt.Sprintf("could not parse template: %s", msg))
+	}
+}
+
+var (
+	renderErrorLocationRe   = regexp.MustCompile(`\(([^():\s]+):(\d+):\d+\)`)
+	renderErrorTemplateRe   = regexp.MustCompile(`template: ([^:\s]+):(\d+):`)
+	yamlErrorLineRe         = regexp.MustCompile(`line (\d+):`)
+	renderedDocumentSplitRe = regexp.MustCompile(`^---`)
+)
+
+func (l *linter) lintRender(ctx context.Context, chart *chartv2.Chart, config TemplateConfig) {
+	// Instrument the `required` calls so we know the location of each of them and we don't stop
+	// the rendering on the first missing value.
+	funcs := template.FuncMap{}
+	chart, err := copyChart(chart, func(name string, data []byte) ([]byte, error) {
+		trees, err := parseTemplate(name, data)
+		if err != nil {
+			return nil, err
+		}
+
+		instrumented := false
+		for _, tree := range trees {
+			walkNode(tree.Root, func(n parse.Node) bool {
+				id, ok := n.(*parse.IdentifierNode)
+				if !ok || id.Ident != lintRequiredFuncName {
+					return true
+				}
+
+				location, _ := tree.ErrorContext(id)
+				file, line := chartRelativePath(name), 0
+				if parts := strings.Split(location, ":"); len(parts) >= 2 {
+					line, _ = strconv.Atoi(parts[len(parts)-2])
+				}
+
+				id.Ident = fmt.Sprintf("%s%d", lintRequiredFuncIDPrefix, len(funcs))
+				funcs[id.Ident] = l.requiredFunc(file, line)
+				instrumented = true
+
+				return true
+			})
+		}
+
+		if !instrumented {
+			return data, nil
+		}
+
+		return treesToSource(name, data, trees), nil
+	})
+	if err != nil {
+		l.add(LintSeverityError, LintRuleTemplate, "", 0, fmt.Sprintf("could not prepare templates: %s", err))
+		return
+	}
+	config.Funcs = funcs
+
+	chart, files, _, err := renderFiles(ctx, chart, config)
+	if err != nil {
+		file, line := "", 0
+		msg := err.Error()
+		match := renderErrorLocationRe.FindStringSubmatch(msg)
+		if match == nil {
+			match = renderErrorTemplateRe.FindStringSubmatch(msg)
+		}
+		if match != nil {
+			file = chartRelativePath(match[1])
+			line, _ = strconv.Atoi(match[2])
+		}
+		l.add(LintSeverityError, LintRuleTemplate, file, line, fmt.Sprintf("could not render template: %s", msg))
+		return
+	}
+
+	kubeVersion, err := kubeVersionMajorMinor(common.DefaultCapabilities.KubeVersion)
+	if err != nil {
+		l.add(LintSeverityError, LintRuleDeprecatedAPI, "", 0, fmt.Sprintf("invalid Kubernetes version: %s", err))
+		return
+	}
+
+	// Add the CRDs so these are also checked.
+	for _, crd := range chart.CRDObjects() {
+		files[crd.Filename] = string(crd.File.Data)
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		if strings.HasSuffix(name, notesFileSuffix) {
+			continue
+		}
+		l.lintManifests(chartRelativePath(name), files[name], kubeVersion)
+	}
+}
+
+// requiredFunc returns a Helm `required` function replacement that instead of failing registers
+// the missing values.
+func (l *linter) requiredFunc(file string, line int) func(msg string, val interface{}) (interface{}, error) {
+	reported := map[string]bool{}
+	return func(msg string, val interface{}) (interface{}, error) {
+		if s, ok := val.(string); val != nil && (!ok || s != "") {
+			return val, nil
+		}
+
+		if !reported[msg] {
+			reported[msg] = true
+			l.add(LintSeverityError, LintRuleRequiredValue, file, line, fmt.Sprintf("missing required value: %s", msg))
+		}
+
+		return "", nil
+	}
+}
+
+func (l *linter) lintManifests(file, content string, kubeVersion [2]int) {
+	for _, doc := range splitRenderedDocuments(content) {
+		obj := map[string]interface{}{}
+		err := yaml.Unmarshal([]byte(doc.content), &obj)
+		if err != nil {
+			line := doc.line
+			if match := yamlErrorLineRe.FindStringSubmatch(err.Error()); match != nil {
+				n, _ := strconv.Atoi(match[1])
+				line += n - 1
+			}
+			l.add(LintSeverityError, LintRuleManifest, file, line, fmt.Sprintf("invalid YAML: %s", err))
+			continue
+		}
+
+		// Empty documents (e.g: only comments).
+		if len(obj) == 0 {
+			continue
+		}
+
+		apiVersion, _ := obj["apiVersion"].(string)
+		if apiVersion == "" {
+			l.add(LintSeverityError, LintRuleManifest, file, doc.line, "apiVersion is not set")
+		}
+		kind, _ := obj["kind"].(string)
+		if kind == "" {
+			l.add(LintSeverityError, LintRuleManifest, file, doc.line, "kind is not set")
+		}
+		if apiVersion == "" || kind == "" {
+			continue
+		}
+
+		lifecycle, ok := lookupAPILifecycle(apiVersion, kind)
+		if !ok || !lifecycle.isDeprecatedOn(kubeVersion) {
+			continue
+		}
+
+		msg := fmt.Sprintf("%s %s is deprecated in %s", apiVersion, kind, formatKubeVersion(lifecycle.Deprecated))
+		if lifecycle.Removed != [2]int{} {
+			msg += fmt.Sprintf(", unavailable in %s", formatKubeVersion(lifecycle.Removed))
+		}
+		if !lifecycle.Replacement.Empty() {
+			r := lifecycle.Replacement
+			msg += fmt.Sprintf("; use %s %s", r.GroupVersion().String(), r.Kind)
+		}
+		l.add(LintSeverityWarning, LintRuleDeprecatedAPI, file, doc.line, msg)
+	}
+}
+
+type renderedDocument struct {
+	// line is the line of the rendered file where the document starts.
+	line    int
+	content string
+}
+
+// splitRenderedDocuments splits a rendered file in its YAML documents keeping the
+// line where each of the documents starts.
+func splitRenderedDocuments(content string) []renderedDocument {
+	docs := []renderedDocument{}
+	current := renderedDocument{line: 1}
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		if renderedDocumentSplitRe.MatchString(line) {
+			docs = append(docs, current)
+			current = renderedDocument{line: i + 2}
+			continue
+		}
+		current.content += line + "\n"
+	}
+	docs = append(docs, current)
+
+	// Remove empty documents.
+	return slices.DeleteFunc(docs, func(d renderedDocument) bool {
+		return strings.TrimSpace(d.content) == ""
+	})
+}
+
+// chartRelativePath returns the path relative to the root chart of a chart file full
+// path (e.g: `mychart/charts/sub/templates/x.yaml` -> `charts/sub/templates/x.yaml`).
+func chartRelativePath(fullPath string) string {
+	_, p, ok := strings.Cut(path.Clean(fullPath), "/")
+	if !ok {
+		return fullPath
+	}
+
+	return p
+}
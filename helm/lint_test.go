@@ -0,0 +1,130 @@
+package helm_test
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/go-helm-template/helm"
+)
+
+func newTestLintChartFS() fstest.MapFS {
+	m := make(fstest.MapFS)
+	m["Chart.yaml"] = &fstest.MapFile{Data: []byte("apiVersion: v2\nname: test-chart\nversion: 0.1.0\nicon: https://example.com/icon.png")}
+
+	return m
+}
+
+func TestLint(t *testing.T) {
+	tests := map[string]struct {
+		chart       func() fstest.MapFS
+		values      map[string]interface{}
+		expFindings []helm.LintFinding
+		expErrors   bool
+	}{
+		"A valid chart shouldn't have findings.": {
+			chart: func() fstest.MapFS {
+				m := newTestLintChartFS()
+				m["templates/cm.yaml"] = &fstest.MapFile{Data: []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: test\n")}
+				return m
+			},
+		},
+
+		"A chart without icon should have an info finding.": {
+			chart:       newTestChartFS,
+			expFindings: []helm.LintFinding{{Severity: helm.LintSeverityInfo, Rule: helm.LintRuleChartfile, File: "Chart.yaml", Message: "icon is recommended"}},
+		},
+
+		"A chart with missing dependencies should have an error finding.": {
+			chart: func() fstest.MapFS {
+				m := newTestLintChartFS()
+				m["Chart.yaml"].Data = append(m["Chart.yaml"].Data, []byte("\ndependencies:\n- name: sub1\n  version: 0.1.0\n")...)
+				return m
+			},
+			expFindings: []helm.LintFinding{{Severity: helm.LintSeverityError, Rule: helm.LintRuleChartfile, File: "Chart.yaml", Message: `chart dependency "sub1" is missing on charts directory`}},
+			expErrors:   true,
+		},
+
+		"A template that can't be parsed should have an error finding with its location.": {
+			chart: func() fstest.MapFS {
+				m := newTestLintChartFS()
+				m["templates/cm.yaml"] = &fstest.MapFile{Data: []byte("apiVersion: v1\nkind: ConfigMap\n{{ if .Values.x }}\n")}
+				m["charts/sub1/Chart.yaml"] = &fstest.MapFile{Data: []byte("apiVersion: v2\nname: sub1\nversion: 0.1.0")}
+				m["charts/sub1/templates/cm.yaml"] = &fstest.MapFile{Data: []byte("\n{{ .Values.x \n")}
+				return m
+			},
+			expFindings: []helm.LintFinding{
+				{Severity: helm.LintSeverityError, Rule: helm.LintRuleTemplate, File: "charts/sub1/templates/cm.yaml", Line: 3, Message: "could not parse template: unclosed action started at test-chart/charts/sub1/templates/cm.yaml:2"},
+				{Severity: helm.LintSeverityError, Rule: helm.LintRuleTemplate, File: "templates/cm.yaml", Line: 4, Message: "could not parse template: unexpected EOF"},
+			},
+			expErrors: true,
+		},
+
+		"A template that fails on render should have an error finding with its location.": {
+			chart: func() fstest.MapFS {
+				m := newTestLintChartFS()
+				m["templates/cm.yaml"] = &fstest.MapFile{Data: []byte("apiVersion: v1\n\nkind: {{ fail \"wrong\" }}\n")}
+				return m
+			},
+			expFindings: []helm.LintFinding{
+				{Severity: helm.LintSeverityError, Rule: helm.LintRuleTemplate, File: "templates/cm.yaml", Line: 3, Message: "could not render template: execution error at (test-chart/templates/cm.yaml:3:9): wrong"},
+			},
+			expErrors: true,
+		},
+
+		"Missing required values should have an error finding for each of them with their location.": {
+			chart: func() fstest.MapFS {
+				m := newTestLintChartFS()
+				m["templates/_helpers.tpl"] = &fstest.MapFile{Data: []byte("{{- define \"test.name\" -}}\n{{ required \"name is required\" .Values.name }}\n{{- end }}\n")}
+				m["templates/cm.yaml"] = &fstest.MapFile{Data: []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: {{ include \"test.name\" . }}\ndata:\n  a: {{ required \"a is required\" .Values.a | quote }}\n  b: {{ .Values.b | required \"b is required\" | quote }}\n")}
+				return m
+			},
+			values: map[string]interface{}{"b": "ok"},
+			expFindings: []helm.LintFinding{
+				{Severity: helm.LintSeverityError, Rule: helm.LintRuleRequiredValue, File: "templates/_helpers.tpl", Line: 2, Message: "missing required value: name is required"},
+				{Severity: helm.LintSeverityError, Rule: helm.LintRuleRequiredValue, File: "templates/cm.yaml", Line: 6, Message: "missing required value: a is required"},
+			},
+			expErrors: true,
+		},
+
+		"Invalid rendered manifests should have error findings with their location.": {
+			chart: func() fstest.MapFS {
+				m := newTestLintChartFS()
+				m["templates/cm.yaml"] = &fstest.MapFile{Data: []byte("# Comment.\n---\napiVersion: v1\nkind: ConfigMap\n---\napiVersion: v1\nkind: ConfigMap\ndata:\n  a: b: c\n---\nkind: ConfigMap\n")}
+				return m
+			},
+			expFindings: []helm.LintFinding{
+				{Severity: helm.LintSeverityError, Rule: helm.LintRuleManifest, File: "templates/cm.yaml", Line: 9, Message: "invalid YAML: error converting YAML to JSON: yaml: line 4: mapping values are not allowed in this context"},
+				{Severity: helm.LintSeverityError, Rule: helm.LintRuleManifest, File: "templates/cm.yaml", Line: 11, Message: "apiVersion is not set"},
+			},
+			expErrors: true,
+		},
+
+		"Deprecated APIs should have warning findings.": {
+			chart: func() fstest.MapFS {
+				m := newTestLintChartFS()
+				m["templates/ingress.yaml"] = &fstest.MapFile{Data: []byte("apiVersion: extensions/v1beta1\nkind: Ingress\nmetadata:\n  name: test\n")}
+				return m
+			},
+			expFindings: []helm.LintFinding{
+				{Severity: helm.LintSeverityWarning, Rule: helm.LintRuleDeprecatedAPI, File: "templates/ingress.yaml", Line: 1, Message: "extensions/v1beta1 Ingress is deprecated in v1.14, unavailable in v1.22; use networking.k8s.io/v1 Ingress"},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			chart := mustLoadChart(test.chart())
+			gotResult, err := helm.Lint(context.TODO(), chart, test.values)
+
+			if assert.NoError(err) {
+				assert.Equal(test.expFindings, gotResult.Findings)
+				assert.Equal(test.expErrors, gotResult.HasErrors())
+			}
+		})
+	}
+}
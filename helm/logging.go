@@ -0,0 +1,222 @@
+package helm
+
+import (
+	"context"
+	"io"
+	"log"
+	"log/slog"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+var (
+	discardLogger = slog.New(slog.DiscardHandler)
+
+	// helmLogsMu serializes the Helm calls with redirected logs, Helm logs using the process global
+	// loggers (`log` and `slog` default loggers).
+	helmLogsMu sync.Mutex
+)
+
+// loggerOrDiscard returns the logger, or a logger that discards everything if missing.
+func loggerOrDiscard(logger *slog.Logger) *slog.Logger {
+	if logger == nil {
+		return discardLogger
+	}
+
+	return logger
+}
+
+// withHelmLogs calls fn sending the Helm internal logs to the logger instead of the global loggers (by
+// default stderr), if the logger is missing the global loggers are not touched.
+//
+// Helm doesn't support injecting a logger, so the global loggers are replaced while fn is called, the
+// logs that don't come from Helm are sent to the previous global loggers. The Helm logs are detected
+// with the caller functions, so this is best-effort. The global loggers are only restored if they have
+// not been replaced in the meantime (e.g: by the application). The redirected calls are serialized, so
+// only the Helm calls that log and don't execute user code (e.g: template functions) should be redirected.
+func withHelmLogs(logger *slog.Logger, fn func()) {
+	if logger == nil || logger == discardLogger {
+		fn()
+		return
+	}
+
+	helmLogsMu.Lock()
+	defer helmLogsMu.Unlock()
+
+	prevLogger := slog.Default()
+	prevOutput := log.Writer()
+	prevFlags := log.Flags()
+
+	logger = logger.With("component", "helm")
+	helmLogger := slog.New(&helmLogsHandler{helm: logger.Handler(), other: prevLogger.Handler()})
+	slog.SetDefault(helmLogger)
+
+	// Setting the slog default logger redirects the `log` package, we keep its original format
+	// for the logs that don't come from Helm.
+	helmWriter := &helmLogsWriter{logger: logger, other: prevOutput, flags: prevFlags, prefix: log.Prefix()}
+	log.SetFlags(prevFlags)
+	log.SetOutput(helmWriter)
+
+	defer func() {
+		ownsSlog := slog.Default() == helmLogger
+		ownsLog := log.Writer() == helmWriter
+
+		if ownsSlog {
+			// Restoring the slog default logger can redirect the `log` package again.
+			output, flags := log.Writer(), log.Flags()
+			slog.SetDefault(prevLogger)
+			if !ownsLog {
+				log.SetOutput(output)
+				log.SetFlags(flags)
+			}
+		}
+
+		if ownsLog {
+			log.SetOutput(prevOutput)
+			log.SetFlags(prevFlags)
+		}
+	}()
+
+	fn()
+}
+
+// isHelmFunc returns true if the function is from Helm.
+func isHelmFunc(name string) bool {
+	return strings.HasPrefix(name, "helm.sh/helm/")
+}
+
+type callerKind int
+
+const (
+	callerKindOther callerKind = iota
+	callerKindHelm
+	callerKindLog
+)
+
+// callerKinds caches the kind of the caller program counters, resolving the functions is expensive.
+var callerKinds sync.Map
+
+// pcCallerKind returns the kind of the function of a program counter, the inlined `log` package
+// functions are skipped.
+func pcCallerKind(pc uintptr) callerKind {
+	if k, ok := callerKinds.Load(pc); ok {
+		return k.(callerKind)
+	}
+
+	kind := callerKindLog
+	frames := runtime.CallersFrames([]uintptr{pc})
+	for {
+		f, more := frames.Next()
+		if !strings.HasPrefix(f.Function, "log.") {
+			kind = callerKindOther
+			if isHelmFunc(f.Function) {
+				kind = callerKindHelm
+			}
+			break
+		}
+		if !more {
+			break
+		}
+	}
+
+	callerKinds.Store(pc, kind)
+	return kind
+}
+
+// helmLogsHandler is a slog handler that sends the Helm logs to the Helm handler and the rest to the
+// other handler.
+type helmLogsHandler struct {
+	helm  slog.Handler
+	other slog.Handler
+}
+
+func (h *helmLogsHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.helm.Enabled(ctx, level) || h.other.Enabled(ctx, level)
+}
+
+func (h *helmLogsHandler) Handle(ctx context.Context, r slog.Record) error {
+	handler := h.other
+	if r.PC != 0 && pcCallerKind(r.PC) == callerKindHelm {
+		handler = h.helm
+	}
+
+	if !handler.Enabled(ctx, r.Level) {
+		return nil
+	}
+
+	return handler.Handle(ctx, r)
+}
+
+func (h *helmLogsHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &helmLogsHandler{helm: h.helm.WithAttrs(attrs), other: h.other.WithAttrs(attrs)}
+}
+
+func (h *helmLogsHandler) WithGroup(name string) slog.Handler {
+	return &helmLogsHandler{helm: h.helm.WithGroup(name), other: h.other.WithGroup(name)}
+}
+
+// helmLogsWriter is the `log` package output that sends the Helm logs to the logger and the rest
+// to the other writer.
+type helmLogsWriter struct {
+	logger *slog.Logger
+	other  io.Writer
+	flags  int
+	prefix string
+}
+
+func (w *helmLogsWriter) Write(p []byte) (int, error) {
+	if !w.calledFromHelm() {
+		return w.other.Write(p)
+	}
+
+	w.logger.Info(w.message(p))
+
+	return len(p), nil
+}
+
+// calledFromHelm returns true if the function that called the `log` package is from Helm.
+func (w *helmLogsWriter) calledFromHelm() bool {
+	pcs := make([]uintptr, 16)
+	for _, pc := range pcs[:runtime.Callers(3, pcs)] {
+		switch pcCallerKind(pc) {
+		case callerKindHelm:
+			return true
+		case callerKindOther:
+			return false
+		}
+	}
+
+	return false
+}
+
+// message returns the message of a `log` package line removing the header (prefix, date, time and file).
+func (w *helmLogsWriter) message(p []byte) string {
+	msg := strings.TrimSuffix(string(p), "\n")
+	if w.flags&log.Lmsgprefix == 0 {
+		msg = strings.TrimPrefix(msg, w.prefix)
+	}
+
+	if w.flags&log.Ldate != 0 {
+		msg = msg[min(len(msg), len("2006/01/02 ")):]
+	}
+
+	switch {
+	case w.flags&log.Lmicroseconds != 0:
+		msg = msg[min(len(msg), len("15:04:05.000000 ")):]
+	case w.flags&log.Ltime != 0:
+		msg = msg[min(len(msg), len("15:04:05 ")):]
+	}
+
+	if w.flags&(log.Lshortfile|log.Llongfile) != 0 {
+		if _, m, ok := strings.Cut(msg, ": "); ok {
+			msg = m
+		}
+	}
+
+	if w.flags&log.Lmsgprefix != 0 {
+		msg = strings.TrimPrefix(msg, w.prefix)
+	}
+
+	return msg
+}
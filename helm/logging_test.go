@@ -0,0 +1,292 @@
+package helm_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"text/template"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/go-helm-template/helm"
+)
+
+// logMessages returns the level, message and component of each JSON log line.
+func logMessages(t *testing.T, logs string) []string {
+	msgs := []string{}
+	for _, l := range strings.Split(strings.TrimSpace(logs), "\n") {
+		if l == "" {
+			continue
+		}
+
+		var entry struct {
+			Level     string `json:"level"`
+			Msg       string `json:"msg"`
+			Component string `json:"component"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(l), &entry))
+
+		msg := entry.Level + " " + entry.Msg
+		if entry.Component != "" {
+			msg += " (" + entry.Component + ")"
+		}
+		msgs = append(msgs, msg)
+	}
+
+	return msgs
+}
+
+func TestLogging(t *testing.T) {
+	chartFS := newTestChartFS()
+	chartFS["values.yaml"] = &fstest.MapFile{Data: []byte("name: test\n")}
+	chartFS["templates/cm.yaml"] = &fstest.MapFile{Data: []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: {{ .Values.name }}\n")}
+
+	tests := map[string]struct {
+		render  bool
+		config  helm.TemplateConfig
+		expLogs []string
+	}{
+		"Loading a chart should log the loading stages.": {
+			expLogs: []string{
+				"DEBUG chart files read",
+				"DEBUG chart loaded",
+			},
+		},
+
+		"Rendering a chart should log the rendering stages.": {
+			render: true,
+			config: helm.TemplateConfig{
+				ShowFiles: []string{"templates/cm.yaml"},
+			},
+			expLogs: []string{
+				"DEBUG dependencies processed",
+				"DEBUG number of dependencies in the chart (helm)",
+				"DEBUG values merged",
+				"DEBUG templates rendered",
+				"DEBUG manifests sorted",
+				"DEBUG manifests filtered",
+			},
+		},
+
+		"Rendering a chart should log the Helm internal logs.": {
+			render: true,
+			config: helm.TemplateConfig{
+				Values: map[string]interface{}{"name": map[string]interface{}{"first": "test"}},
+			},
+			expLogs: []string{
+				"DEBUG dependencies processed",
+				"INFO warning: skipped value for test-chart.name: Not a table. (helm)",
+				"DEBUG number of dependencies in the chart (helm)",
+				"DEBUG values merged",
+				"DEBUG templates rendered",
+				"DEBUG manifests sorted",
+				"WARN chart default value is skipped because it's not a table",
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			var logs bytes.Buffer
+			logger := slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+			chart, err := helm.LoadChartWithConfig(context.TODO(), chartFS, helm.LoadChartConfig{Logger: logger})
+			require.NoError(err)
+
+			if test.render {
+				logs.Reset()
+				config := test.config
+				config.ReleaseName = "test"
+				config.Chart = chart
+				config.Logger = logger
+				_, err := helm.Render(context.TODO(), config)
+				require.NoError(err)
+			}
+
+			assert.Equal(test.expLogs, logMessages(t, logs.String()))
+		})
+	}
+}
+
+func TestLoggingConcurrentRenders(t *testing.T) {
+	const renders = 10
+
+	chartFS := newTestChartFS()
+	values := ""
+	for i := range renders {
+		values += fmt.Sprintf("key%d: test\n", i)
+	}
+	chartFS["values.yaml"] = &fstest.MapFile{Data: []byte(values)}
+	chartFS["templates/cm.yaml"] = &fstest.MapFile{Data: []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: test\n")}
+	chart := mustLoadChart(chartFS)
+
+	logs := make([]bytes.Buffer, renders)
+	var wg sync.WaitGroup
+	for i := range renders {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logger := slog.New(slog.NewJSONHandler(&logs[i], nil))
+			_, err := helm.Render(context.TODO(), helm.TemplateConfig{
+				Chart:       chart,
+				ReleaseName: "test",
+				Values:      map[string]interface{}{fmt.Sprintf("key%d", i): map[string]interface{}{"a": "b"}},
+				Logger:      logger,
+			})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	// Each render should only receive its own Helm logs.
+	for i := range renders {
+		helmLogs := []string{}
+		for _, msg := range logMessages(t, logs[i].String()) {
+			if strings.HasSuffix(msg, "(helm)") {
+				helmLogs = append(helmLogs, msg)
+			}
+		}
+		assert.Equal(t, []string{fmt.Sprintf("INFO warning: skipped value for test-chart.key%d: Not a table. (helm)", i)}, helmLogs)
+	}
+}
+
+func TestLoggingReentrantRender(t *testing.T) {
+	chartFS := newTestChartFS()
+	chartFS["templates/cm.yaml"] = &fstest.MapFile{Data: []byte("nested: {{ nested | quote }}\n")}
+	chart := mustLoadChart(chartFS)
+	logger := slog.New(slog.DiscardHandler)
+
+	nestedFS := newTestChartFS()
+	nestedFS["templates/cm.yaml"] = &fstest.MapFile{Data: []byte("name: {{ .Release.Name }}\n")}
+	nestedChart := mustLoadChart(nestedFS)
+
+	done := make(chan error)
+	go func() {
+		_, err := helm.Render(context.TODO(), helm.TemplateConfig{
+			Chart:       chart,
+			ReleaseName: "test",
+			Logger:      logger,
+			Funcs: template.FuncMap{
+				"nested": func() (string, error) {
+					result, err := helm.Render(context.TODO(), helm.TemplateConfig{Chart: nestedChart, ReleaseName: "nested", Logger: logger})
+					if err != nil {
+						return "", err
+					}
+					return result.Manifests, nil
+				},
+			},
+		})
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("render called from a template function should not block")
+	}
+}
+
+func TestLoggingConcurrentHostLogs(t *testing.T) {
+	const (
+		renders  = 10
+		hostLogs = 200
+	)
+
+	// The application global loggers.
+	var hostLogsBuf bytes.Buffer
+	prevLogger := slog.Default()
+	hostLogger := slog.New(slog.NewJSONHandler(&hostLogsBuf, nil))
+	slog.SetDefault(hostLogger)
+	hostOutput := log.Writer()
+	t.Cleanup(func() { slog.SetDefault(prevLogger) })
+
+	chartFS := newTestChartFS()
+	chartFS["values.yaml"] = &fstest.MapFile{Data: []byte("key: test\n")}
+	chartFS["templates/cm.yaml"] = &fstest.MapFile{Data: []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: test\n")}
+	chart := mustLoadChart(chartFS)
+
+	var hostWG sync.WaitGroup
+	hostWG.Add(1)
+	go func() {
+		defer hostWG.Done()
+		for i := range hostLogs {
+			if i%2 == 0 {
+				slog.Info("host slog")
+			} else {
+				log.Print("host log")
+			}
+		}
+	}()
+
+	logs := make([]bytes.Buffer, renders)
+	var wg sync.WaitGroup
+	for i := range renders {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := helm.Render(context.TODO(), helm.TemplateConfig{
+				Chart:       chart,
+				ReleaseName: "test",
+				Values:      map[string]interface{}{"key": map[string]interface{}{"a": "b"}},
+				Logger:      slog.New(slog.NewJSONHandler(&logs[i], &slog.HandlerOptions{Level: slog.LevelDebug})),
+			})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	hostWG.Wait()
+
+	// The application logs should only go to the application loggers.
+	gotHostLogs := logMessages(t, hostLogsBuf.String())
+	assert.Len(t, gotHostLogs, hostLogs)
+	for _, msg := range gotHostLogs {
+		assert.Contains(t, []string{"INFO host slog", "INFO host log"}, msg)
+	}
+
+	// The renders should only receive the Helm logs.
+	for i := range renders {
+		for _, msg := range logMessages(t, logs[i].String()) {
+			assert.NotContains(t, msg, "host")
+		}
+		assert.Contains(t, logMessages(t, logs[i].String()), "INFO warning: skipped value for test-chart.key: Not a table. (helm)")
+	}
+
+	// The application global loggers should be restored.
+	assert.Same(t, hostLogger, slog.Default())
+	assert.Equal(t, hostOutput, log.Writer())
+}
+
+func TestLoggingWithoutLogger(t *testing.T) {
+	var hostLogsBuf bytes.Buffer
+	prevLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&hostLogsBuf, nil)))
+	t.Cleanup(func() { slog.SetDefault(prevLogger) })
+
+	chartFS := newTestChartFS()
+	chartFS["values.yaml"] = &fstest.MapFile{Data: []byte("key: test\n")}
+	chart := mustLoadChart(chartFS)
+
+	result, err := helm.Render(context.TODO(), helm.TemplateConfig{
+		Chart:       chart,
+		ReleaseName: "test",
+		Values:      map[string]interface{}{"key": map[string]interface{}{"a": "b"}},
+	})
+	require.NoError(t, err)
+
+	// Without logger the global loggers are not replaced, the warnings are still returned.
+	assert.Equal(t, []string{"INFO warning: skipped value for test-chart.key: Not a table."}, logMessages(t, hostLogsBuf.String()))
+	assert.Len(t, result.Warnings, 1)
+}
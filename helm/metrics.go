@@ -0,0 +1,93 @@
+package helm
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// MetricsRecorder records the chart loading and rendering metrics, it's called on every chart load
+// and render.
+//
+// A ready to use Prometheus implementation is available on `github.com/slok/go-helm-template/metrics/prometheus`.
+type MetricsRecorder interface {
+	// ObserveChartLoad is called after loading a chart.
+	ObserveChartLoad(ctx context.Context, m ChartLoadMetrics)
+	// ObserveRender is called after rendering a chart.
+	ObserveRender(ctx context.Context, m RenderMetrics)
+}
+
+// FailureReason is the reason of a chart load or render failure.
+type FailureReason string
+
+const (
+	// FailureReasonInvalidConfig is used when the configuration is invalid.
+	FailureReasonInvalidConfig FailureReason = "invalid-config"
+	// FailureReasonLoad is used when the chart can't be read or loaded.
+	FailureReasonLoad FailureReason = "load"
+	// FailureReasonRender is used when the chart can't be rendered (e.g: template errors).
+	FailureReasonRender FailureReason = "render"
+	// FailureReasonMissingValue is used when the rendering fails due to a missing value on strict mode.
+	FailureReasonMissingValue FailureReason = "missing-value"
+	// FailureReasonSandbox is used when the chart breaks the sandbox policy.
+	FailureReasonSandbox FailureReason = "sandbox"
+	// FailureReasonValidation is used when the rendered manifests are invalid.
+	FailureReasonValidation FailureReason = "validation"
+)
+
+// ChartLoadMetrics are the metrics of a chart load.
+type ChartLoadMetrics struct {
+	// Chart is the loaded chart name, empty if the chart couldn't be loaded.
+	Chart string
+	// Duration is the time spent loading the chart.
+	Duration time.Duration
+	// FailureReason is the reason of the failure, empty if the chart has been loaded.
+	FailureReason FailureReason
+}
+
+// RenderMetrics are the metrics of a chart render.
+type RenderMetrics struct {
+	// Chart is the rendered chart name.
+	Chart string
+	// Duration is the time spent rendering the chart.
+	Duration time.Duration
+	// OutputBytes is the size of the rendered manifests.
+	OutputBytes int
+	// FailureReason is the reason of the failure, empty if the chart has been rendered.
+	FailureReason FailureReason
+}
+
+// noopMetricsRecorder is used when there is no metrics recorder.
+type noopMetricsRecorder struct{}
+
+func (noopMetricsRecorder) ObserveChartLoad(ctx context.Context, m ChartLoadMetrics) {}
+func (noopMetricsRecorder) ObserveRender(ctx context.Context, m RenderMetrics)       {}
+
+// metricsRecorderOrNoop returns the metrics recorder, or a recorder that doesn't record anything if missing.
+func metricsRecorderOrNoop(r MetricsRecorder) MetricsRecorder {
+	if r == nil {
+		return noopMetricsRecorder{}
+	}
+
+	return r
+}
+
+// renderFailureReason returns the failure reason of a render error, using the default reason when
+// the error doesn't have a specific reason.
+func renderFailureReason(err error, defaultReason FailureReason) FailureReason {
+	var (
+		missingErr    *MissingValueError
+		sandboxErr    *SandboxPolicyError
+		validationErr *ValidationError
+	)
+	switch {
+	case errors.As(err, &missingErr):
+		return FailureReasonMissingValue
+	case errors.As(err, &sandboxErr):
+		return FailureReasonSandbox
+	case errors.As(err, &validationErr):
+		return FailureReasonValidation
+	}
+
+	return defaultReason
+}
@@ -0,0 +1,85 @@
+package helm_test
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/go-helm-template/helm"
+)
+
+type testMetricsRecorder struct {
+	loads   []helm.ChartLoadMetrics
+	renders []helm.RenderMetrics
+}
+
+func (t *testMetricsRecorder) ObserveChartLoad(ctx context.Context, m helm.ChartLoadMetrics) {
+	m.Duration = 0
+	t.loads = append(t.loads, m)
+}
+
+func (t *testMetricsRecorder) ObserveRender(ctx context.Context, m helm.RenderMetrics) {
+	m.Duration = 0
+	t.renders = append(t.renders, m)
+}
+
+func TestMetrics(t *testing.T) {
+	chartFS := newTestChartFS()
+	chartFS["templates/cm.yaml"] = &fstest.MapFile{Data: []byte(`{{ if .Values.fail }}{{ fail "failed" }}{{ end }}name: {{ .Values.name }}`)}
+
+	tests := map[string]struct {
+		config     helm.TemplateConfig
+		expRenders []helm.RenderMetrics
+	}{
+		"A successful render should record the output size.": {
+			config: helm.TemplateConfig{
+				ReleaseName: "test",
+				Values:      map[string]interface{}{"name": "test"},
+			},
+			expRenders: []helm.RenderMetrics{{Chart: "test-chart", OutputBytes: 54}},
+		},
+
+		"An invalid configuration should record the failure reason.": {
+			config:     helm.TemplateConfig{},
+			expRenders: []helm.RenderMetrics{{FailureReason: helm.FailureReasonInvalidConfig}},
+		},
+
+		"A template error should record the failure reason.": {
+			config: helm.TemplateConfig{
+				ReleaseName: "test",
+				Values:      map[string]interface{}{"fail": true},
+			},
+			expRenders: []helm.RenderMetrics{{Chart: "test-chart", FailureReason: helm.FailureReasonRender}},
+		},
+
+		"A missing value on strict mode should record the failure reason.": {
+			config: helm.TemplateConfig{
+				ReleaseName:   "test",
+				MissingValues: helm.MissingValuesModeError,
+			},
+			expRenders: []helm.RenderMetrics{{Chart: "test-chart", FailureReason: helm.FailureReasonMissingValue}},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			recorder := &testMetricsRecorder{}
+			chart, err := helm.LoadChartWithConfig(context.TODO(), chartFS, helm.LoadChartConfig{Metrics: recorder})
+			require.NoError(err)
+
+			config := test.config
+			config.Chart = chart
+			config.Metrics = recorder
+			_, _ = helm.Render(context.TODO(), config)
+
+			assert.Equal([]helm.ChartLoadMetrics{{Chart: "test-chart"}}, recorder.loads)
+			assert.Equal(test.expRenders, recorder.renders)
+		})
+	}
+}
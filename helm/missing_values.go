@@ -0,0 +1,162 @@
+package helm
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"text/template"
+	"text/template/parse"
+
+	"helm.sh/helm/v4/pkg/chart/common"
+	chartv2 "helm.sh/helm/v4/pkg/chart/v2"
+)
+
+const valuesCheckFuncName = "goHelmTemplateCheckValues"
+
+// MissingValuesMode is how the missing values referenced by the templates are handled.
+type MissingValuesMode string
+
+const (
+	// MissingValuesModeIgnore renders the missing values as empty, this is the default Helm behavior.
+	MissingValuesModeIgnore MissingValuesMode = ""
+	// MissingValuesModeError fails the rendering on the first missing value, like Helm strict
+	// mode (`missingkey=error`).
+	MissingValuesModeError MissingValuesMode = "error"
+	// MissingValuesModeWarn renders the missing values as empty and returns all of them on the
+	// render result (`RenderResult.MissingValues`).
+	MissingValuesModeWarn MissingValuesMode = "warn"
+)
+
+func (m MissingValuesMode) validate() error {
+	switch m {
+	case MissingValuesModeIgnore, MissingValuesModeError, MissingValuesModeWarn:
+		return nil
+	}
+
+	return fmt.Errorf("unknown mode %q", m)
+}
+
+// MissingValue is a values key referenced by a template that doesn't exist.
+type MissingValue struct {
+	// Template is the template that references the value (e.g: `mychart/templates/deployment.yaml`).
+	Template string
+	// Line is the template line where the value is referenced.
+	Line int
+	// Column is the template column where the value is referenced.
+	Column int
+	// Path is the values path of the missing key (e.g: `image.tagg`, `subchart.replicaCount`).
+	Path string
+}
+
+func (m MissingValue) String() string {
+	return fmt.Sprintf("%s:%d:%d: missing value %q", m.Template, m.Line, m.Column, m.Path)
+}
+
+// MissingValueError is the error returned when a template references a missing value
+// and the missing values mode is `MissingValuesModeError`.
+type MissingValueError struct {
+	MissingValue
+}
+
+func (e *MissingValueError) Error() string {
+	return e.MissingValue.String()
+}
+
+// valuesChecker is a render instrument that checks the values keys accessed by the templates exist,
+// like `missingkey=error` but only for the values and returning the values path of the missing keys.
+//
+// Note: The values accessed inside `tpl` templates are not checked.
+type valuesChecker struct {
+	mode    MissingValuesMode
+	paths   valuesPaths
+	missing []MissingValue
+}
+
+func newValuesChecker(mode MissingValuesMode) *valuesChecker {
+	return &valuesChecker{
+		mode:  mode,
+		paths: valuesPaths{},
+	}
+}
+
+func (c *valuesChecker) instrumentTemplate(fullName string, source []byte, trees map[string]*parse.Tree) error {
+	for _, tree := range trees {
+		instrumentValuesRefs(tree.Root, func(n parse.Node, refs []valueRef) []parse.Node {
+			nodes := []parse.Node{}
+			for _, ref := range refs {
+				// Like `missingkey`, only the field chains are checked (`index` returns empty values).
+				if len(ref.fields) == 0 || ref.index {
+					continue
+				}
+
+				line, col := sourcePosition(source, int(ref.base.Position()))
+				nodes = append(nodes, newValuesFuncCallNode(valuesCheckFuncName, []string{fullName, strconv.Itoa(line), strconv.Itoa(col)}, ref))
+			}
+
+			return nodes
+		})
+	}
+
+	return nil
+}
+
+func (c *valuesChecker) funcs() template.FuncMap {
+	return template.FuncMap{valuesCheckFuncName: c.check}
+}
+
+func (c *valuesChecker) prepareValues(chart *chartv2.Chart, values common.Values) {
+	c.paths.index(reflect.ValueOf(values["Values"]), nil)
+}
+
+// check is the template function that checks the fields accessed from a template value (e.g: `.`, `$v`)
+// exist on the values.
+func (c *valuesChecker) check(tpl, line, col string, base interface{}, fields ...string) (string, error) {
+	_, missing, ok := c.paths.resolve(base, fields)
+	if !ok || missing == nil {
+		return "", nil
+	}
+
+	m := MissingValue{Template: tpl, Path: formatFieldPath(missing)}
+	m.Line, _ = strconv.Atoi(line)
+	m.Column, _ = strconv.Atoi(col)
+	if !slices.Contains(c.missing, m) {
+		c.missing = append(c.missing, m)
+	}
+
+	if c.mode == MissingValuesModeError {
+		return "", &MissingValueError{MissingValue: m}
+	}
+
+	return "", nil
+}
+
+// renderError returns the missing value error if the rendering failed due to a missing value,
+// otherwise the original rendering error.
+func (c *valuesChecker) renderError(err error) error {
+	if c.mode == MissingValuesModeError && len(c.missing) > 0 {
+		return &MissingValueError{MissingValue: c.missing[0]}
+	}
+
+	return err
+}
+
+// missingValues returns the missing values sorted by template and position.
+func (c *valuesChecker) missingValues() []MissingValue {
+	missing := slices.Clone(c.missing)
+	if missing == nil {
+		missing = []MissingValue{}
+	}
+
+	slices.SortFunc(missing, func(a, b MissingValue) int {
+		return cmp.Or(
+			cmp.Compare(a.Template, b.Template),
+			cmp.Compare(a.Line, b.Line),
+			cmp.Compare(a.Column, b.Column),
+			cmp.Compare(a.Path, b.Path),
+		)
+	})
+
+	return missing
+}
@@ -0,0 +1,100 @@
+package helm_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/go-helm-template/helm"
+)
+
+func newTestMissingValuesChart() *helm.Chart {
+	chartFS := newTestChartFS()
+	chartFS["values.yaml"] = &fstest.MapFile{Data: []byte("replicaCount: 1\nimage:\n  tag: v1\nports:\n- name: http\n")}
+	chartFS["templates/cm.yaml"] = &fstest.MapFile{Data: []byte(`replicas: {{ .Values.replicaCont }}
+{{- $image := .Values.image }}
+tag: {{ $image.tagg }}
+missing: {{ index .Values "indexed" }}
+{{- range .Values.ports }}
+port: {{ .name }}-{{ .number }}
+{{- end }}
+`)}
+	chartFS["charts/sub1/Chart.yaml"] = &fstest.MapFile{Data: []byte("apiVersion: v2\nname: sub1\nversion: 0.1.0")}
+	chartFS["charts/sub1/templates/cm.yaml"] = &fstest.MapFile{Data: []byte(`name: {{ .Values.name }}`)}
+
+	return mustLoadChart(chartFS)
+}
+
+func TestRenderMissingValues(t *testing.T) {
+	tests := map[string]struct {
+		mode       helm.MissingValuesMode
+		values     map[string]interface{}
+		expMissing []helm.MissingValue
+		expErr     *helm.MissingValueError
+		expErrMsg  string
+	}{
+		"Ignoring missing values should not report them.": {
+			mode: helm.MissingValuesModeIgnore,
+		},
+
+		"Warning on missing values should report all of them without failing.": {
+			mode: helm.MissingValuesModeWarn,
+			expMissing: []helm.MissingValue{
+				{Template: "test-chart/charts/sub1/templates/cm.yaml", Line: 1, Column: 17, Path: "sub1.name"},
+				{Template: "test-chart/templates/cm.yaml", Line: 1, Column: 21, Path: "replicaCont"},
+				{Template: "test-chart/templates/cm.yaml", Line: 3, Column: 15, Path: "image.tagg"},
+				{Template: "test-chart/templates/cm.yaml", Line: 6, Column: 22, Path: "ports[0].number"},
+			},
+		},
+
+		"Warning on missing values should not report the existing values.": {
+			mode: helm.MissingValuesModeWarn,
+			values: map[string]interface{}{
+				"replicaCont": 2,
+				"image":       map[string]interface{}{"tagg": "v2"},
+				"ports":       []interface{}{map[string]interface{}{"name": "http", "number": 80}},
+				"sub1":        map[string]interface{}{"name": "test"},
+			},
+			expMissing: []helm.MissingValue{},
+		},
+
+		"Failing on missing values should return the first missing value.": {
+			mode:      helm.MissingValuesModeError,
+			values:    map[string]interface{}{"sub1": map[string]interface{}{"name": "test"}},
+			expErr:    &helm.MissingValueError{MissingValue: helm.MissingValue{Template: "test-chart/templates/cm.yaml", Line: 1, Column: 21, Path: "replicaCont"}},
+			expErrMsg: `could not render helm chart correctly: test-chart/templates/cm.yaml:1:21: missing value "replicaCont"`,
+		},
+
+		"An invalid mode should fail.": {
+			mode:      "invalid",
+			expErrMsg: `invalid configuration: invalid missing values mode: unknown mode "invalid"`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			gotResult, err := helm.Render(context.TODO(), helm.TemplateConfig{
+				ReleaseName:   "test",
+				Chart:         newTestMissingValuesChart(),
+				Values:        test.values,
+				MissingValues: test.mode,
+			})
+
+			if test.expErrMsg != "" {
+				assert.EqualError(err, test.expErrMsg)
+				if test.expErr != nil {
+					var gotErr *helm.MissingValueError
+					assert.True(errors.As(err, &gotErr))
+					assert.Equal(test.expErr, gotErr)
+				}
+			} else if assert.NoError(err) {
+				assert.Equal(test.expMissing, gotResult.MissingValues)
+			}
+		})
+	}
+}
@@ -0,0 +1,265 @@
+package helm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"go.opentelemetry.io/otel/trace"
+	"helm.sh/helm/v4/pkg/registry"
+)
+
+// maxOCIIndexDepth is the maximum depth of nested OCI image indexes.
+const maxOCIIndexDepth = 5
+
+// LoadChartOCILayoutConfig is the configuration used to load a chart from an OCI image layout.
+type LoadChartOCILayoutConfig struct {
+	// Logger when set will receive the debug events of the chart loading and the Helm internal
+	// logs, instead of writing them to stderr (see `LoadChartConfig.Logger`).
+	Logger *slog.Logger
+	// TracerProvider when set will be used to trace the chart loading with OpenTelemetry.
+	TracerProvider trace.TracerProvider
+	// Metrics when set will record the chart loading metrics.
+	Metrics MetricsRecorder
+}
+
+// LoadChartOCILayout loads a chart stored as an OCI artifact (like `helm push` does) from an OCI image
+// layout (e.g: `oras copy --to-oci-layout`), without any network access. The fs.FS root must be the OCI
+// image layout root (the one with `oci-layout` and `index.json` files).
+//
+// The reference can be the tag (e.g: `1.2.3`), a full reference with a tag (e.g: `registry.io/charts/app:1.2.3`)
+// matched by the layout references names, or the manifest digest (e.g: `sha256:3f0a...`). The full references
+// names need to match exactly, and the tags match the references tags, if a tag matches multiple manifests the
+// loading fails as ambiguous. The digests and sizes of all the read blobs are verified.
+func LoadChartOCILayout(ctx context.Context, layout fs.FS, reference string, config LoadChartOCILayoutConfig) (chart *Chart, err error) {
+	ctx, span := startSpan(ctx, config.TracerProvider, "LoadChartOCILayout")
+	defer func() { endSpan(span, err) }()
+
+	start := time.Now()
+	defer func() { observeChartLoad(ctx, config.Metrics, start, chart, err) }()
+
+	logger := loggerOrDiscard(config.Logger)
+
+	index, err := readOCILayoutIndex(layout)
+	if err != nil {
+		return nil, err
+	}
+
+	desc, err := findOCIReference(index, reference)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := findOCIChartManifest(layout, desc, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	layer, err := findOCIChartLayer(manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := readOCIBlob(layout, layer)
+	if err != nil {
+		return nil, fmt.Errorf("could not read chart layer: %w", err)
+	}
+	logger.DebugContext(ctx, "chart layer read", "reference", reference, "digest", layer.Digest.String())
+
+	return loadChartArchive(ctx, data, nil, logger)
+}
+
+// readOCILayoutIndex reads the OCI image layout index.
+func readOCILayoutIndex(layout fs.FS) (*ocispec.Index, error) {
+	data, err := fs.ReadFile(layout, ocispec.ImageLayoutFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not read OCI image layout file, it's not an OCI image layout: %w", err)
+	}
+
+	l := &ocispec.ImageLayout{}
+	err = json.Unmarshal(data, l)
+	if err != nil {
+		return nil, fmt.Errorf("could not unmarshal OCI image layout file: %w", err)
+	}
+	if l.Version != ocispec.ImageLayoutVersion {
+		return nil, fmt.Errorf("unsupported OCI image layout version %q", l.Version)
+	}
+
+	data, err = fs.ReadFile(layout, ocispec.ImageIndexFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not read OCI image layout index: %w", err)
+	}
+
+	index := &ocispec.Index{}
+	err = json.Unmarshal(data, index)
+	if err != nil {
+		return nil, fmt.Errorf("could not unmarshal OCI image layout index: %w", err)
+	}
+
+	return index, nil
+}
+
+// findOCIReference returns the OCI image layout index descriptor of the reference.
+func findOCIReference(index *ocispec.Index, reference string) (ocispec.Descriptor, error) {
+	// Digest references (e.g: `sha256:3f0a...` or `registry.io/charts/app@sha256:3f0a...`).
+	ref := ""
+	if i := strings.LastIndex(reference, "@"); i >= 0 {
+		ref = reference[i+1:]
+	} else if strings.HasPrefix(reference, string(digest.Canonical)+":") {
+		ref = reference
+	}
+
+	if ref != "" {
+		d, err := digest.Parse(ref)
+		if err != nil {
+			return ocispec.Descriptor{}, fmt.Errorf("invalid reference digest: %w", err)
+		}
+
+		for _, m := range index.Manifests {
+			if m.Digest == d {
+				return m, nil
+			}
+		}
+
+		return ocispec.Descriptor{}, fmt.Errorf("reference %q not found on the OCI image layout", reference)
+	}
+
+	// The references names can be the tag or a full reference, the full references need to match
+	// exactly and the tags match the reference tag.
+	var exact, tagged []ocispec.Descriptor
+	for _, m := range index.Manifests {
+		name := m.Annotations[ocispec.AnnotationRefName]
+		switch {
+		case name == "":
+		case name == reference:
+			exact = append(exact, m)
+		case ociReferenceTag(name) == name && name == ociReferenceTag(reference),
+			ociReferenceTag(reference) == reference && reference == ociReferenceTag(name):
+			tagged = append(tagged, m)
+		}
+	}
+
+	matches := exact
+	if len(matches) == 0 {
+		matches = tagged
+	}
+
+	switch {
+	case len(matches) == 0:
+		return ocispec.Descriptor{}, fmt.Errorf("reference %q not found on the OCI image layout", reference)
+	// The same manifest can have multiple references names.
+	case slices.ContainsFunc(matches[1:], func(m ocispec.Descriptor) bool { return m.Digest != matches[0].Digest }):
+		return ocispec.Descriptor{}, fmt.Errorf("reference %q is ambiguous, it matches %d manifests on the OCI image layout, use the full reference or the digest", reference, len(matches))
+	}
+
+	return matches[0], nil
+}
+
+// ociReferenceTag returns the tag of an OCI reference (e.g: `1.2.3` for `registry.io:5000/charts/app:1.2.3`),
+// the reference itself if it's a tag, or empty if the reference has no tag.
+func ociReferenceTag(reference string) string {
+	tag := reference[strings.LastIndex(reference, ":")+1:]
+	if strings.Contains(tag, "/") {
+		return ""
+	}
+
+	return tag
+}
+
+// findOCIChartManifest returns the Helm chart manifest of the descriptor, if the descriptor is an image index
+// the first Helm chart manifest of the index is used.
+func findOCIChartManifest(layout fs.FS, desc ocispec.Descriptor, depth int) (*ocispec.Manifest, error) {
+	switch desc.MediaType {
+	case ocispec.MediaTypeImageManifest:
+		data, err := readOCIBlob(layout, desc)
+		if err != nil {
+			return nil, fmt.Errorf("could not read manifest: %w", err)
+		}
+
+		manifest := &ocispec.Manifest{}
+		err = json.Unmarshal(data, manifest)
+		if err != nil {
+			return nil, fmt.Errorf("could not unmarshal manifest: %w", err)
+		}
+
+		if manifest.Config.MediaType != registry.ConfigMediaType {
+			return nil, fmt.Errorf("manifest %s is not a Helm chart, config media type is %q", desc.Digest, manifest.Config.MediaType)
+		}
+
+		return manifest, nil
+
+	case ocispec.MediaTypeImageIndex:
+		if depth >= maxOCIIndexDepth {
+			return nil, fmt.Errorf("too many nested image indexes")
+		}
+
+		data, err := readOCIBlob(layout, desc)
+		if err != nil {
+			return nil, fmt.Errorf("could not read image index: %w", err)
+		}
+
+		index := &ocispec.Index{}
+		err = json.Unmarshal(data, index)
+		if err != nil {
+			return nil, fmt.Errorf("could not unmarshal image index: %w", err)
+		}
+
+		for _, m := range index.Manifests {
+			if m.ArtifactType != "" && m.ArtifactType != registry.ConfigMediaType {
+				continue
+			}
+
+			manifest, err := findOCIChartManifest(layout, m, depth+1)
+			if err == nil {
+				return manifest, nil
+			}
+		}
+
+		return nil, fmt.Errorf("image index %s has no Helm chart manifest", desc.Digest)
+	}
+
+	return nil, fmt.Errorf("unsupported media type %q", desc.MediaType)
+}
+
+// findOCIChartLayer returns the chart archive layer of a Helm chart manifest.
+func findOCIChartLayer(manifest *ocispec.Manifest) (ocispec.Descriptor, error) {
+	for _, l := range manifest.Layers {
+		if l.MediaType == registry.ChartLayerMediaType || l.MediaType == registry.LegacyChartLayerMediaType {
+			return l, nil
+		}
+	}
+
+	return ocispec.Descriptor{}, fmt.Errorf("manifest has no Helm chart layer")
+}
+
+// readOCIBlob reads a blob of the OCI image layout verifying its size and digest.
+func readOCIBlob(layout fs.FS, desc ocispec.Descriptor) ([]byte, error) {
+	// Validated digests can be safely used as paths.
+	err := desc.Digest.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid digest %q: %w", desc.Digest, err)
+	}
+
+	data, err := fs.ReadFile(layout, path.Join(ocispec.ImageBlobsDir, desc.Digest.Algorithm().String(), desc.Digest.Encoded()))
+	if err != nil {
+		return nil, fmt.Errorf("could not read blob %s: %w", desc.Digest, err)
+	}
+
+	if int64(len(data)) != desc.Size {
+		return nil, fmt.Errorf("blob %s size mismatch: expected %d, got %d", desc.Digest, desc.Size, len(data))
+	}
+
+	if got := desc.Digest.Algorithm().FromBytes(data); got != desc.Digest {
+		return nil, fmt.Errorf("blob %s digest mismatch: got %s", desc.Digest, got)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,208 @@
+package helm_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"testing/fstest"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/go-helm-template/helm"
+)
+
+// testOCILayout is an in memory OCI image layout.
+type testOCILayout struct {
+	t  *testing.T
+	fs fstest.MapFS
+}
+
+func newTestOCILayout(t *testing.T) *testOCILayout {
+	return &testOCILayout{
+		t: t,
+		fs: fstest.MapFS{
+			ocispec.ImageLayoutFile: &fstest.MapFile{Data: []byte(`{"imageLayoutVersion":"1.0.0"}`)},
+		},
+	}
+}
+
+func (l *testOCILayout) blob(mediaType string, data []byte) ocispec.Descriptor {
+	d := digest.FromBytes(data)
+	l.fs["blobs/sha256/"+d.Encoded()] = &fstest.MapFile{Data: data}
+	return ocispec.Descriptor{MediaType: mediaType, Digest: d, Size: int64(len(data))}
+}
+
+func (l *testOCILayout) jsonBlob(mediaType string, v interface{}) ocispec.Descriptor {
+	data, err := json.Marshal(v)
+	require.NoError(l.t, err)
+	return l.blob(mediaType, data)
+}
+
+func (l *testOCILayout) chartManifest(configMediaType string, chartArchive []byte) ocispec.Descriptor {
+	return l.jsonBlob(ocispec.MediaTypeImageManifest, ocispec.Manifest{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Config:    l.blob(configMediaType, []byte(`{"name":"test-chart","version":"0.1.0"}`)),
+		Layers:    []ocispec.Descriptor{l.blob("application/vnd.cncf.helm.chart.content.v1.tar+gzip", chartArchive)},
+	})
+}
+
+func (l *testOCILayout) index(descs ...ocispec.Descriptor) fstest.MapFS {
+	data, err := json.Marshal(ocispec.Index{MediaType: ocispec.MediaTypeImageIndex, Manifests: descs})
+	require.NoError(l.t, err)
+	l.fs[ocispec.ImageIndexFile] = &fstest.MapFile{Data: data}
+	return l.fs
+}
+
+func withRefName(desc ocispec.Descriptor, name string) ocispec.Descriptor {
+	desc.Annotations = map[string]string{ocispec.AnnotationRefName: name}
+	return desc
+}
+
+func TestLoadChartOCILayout(t *testing.T) {
+	const helmConfigMediaType = "application/vnd.cncf.helm.config.v1+json"
+
+	newArchive := func(t *testing.T, name string) []byte {
+		return newTestChartArchive(t, "test-chart", []testArchiveFile{
+			{"Chart.yaml", "apiVersion: v2\nname: test-chart\nversion: 0.1.0"},
+			{"templates/cm.yaml", "name: " + name},
+		})
+	}
+
+	tests := map[string]struct {
+		layout func(t *testing.T) (layout fstest.MapFS, reference string)
+		expOut string
+		expErr bool
+	}{
+		"A chart should be loaded by its tag.": {
+			layout: func(t *testing.T) (fstest.MapFS, string) {
+				l := newTestOCILayout(t)
+				return l.index(
+					withRefName(l.chartManifest(helmConfigMediaType, newArchive(t, "a")), "0.1.0"),
+					withRefName(l.chartManifest(helmConfigMediaType, newArchive(t, "b")), "0.2.0"),
+				), "0.2.0"
+			},
+			expOut: "---\n# Source: test-chart/templates/cm.yaml\nname: b\n",
+		},
+
+		"A chart should be loaded by its full reference.": {
+			layout: func(t *testing.T) (fstest.MapFS, string) {
+				l := newTestOCILayout(t)
+				return l.index(withRefName(l.chartManifest(helmConfigMediaType, newArchive(t, "a")), "0.1.0")), "registry.example.com/charts/test-chart:0.1.0"
+			},
+			expOut: "---\n# Source: test-chart/templates/cm.yaml\nname: a\n",
+		},
+
+		"A chart should be loaded by its full reference when the layout has full references.": {
+			layout: func(t *testing.T) (fstest.MapFS, string) {
+				l := newTestOCILayout(t)
+				return l.index(
+					withRefName(l.chartManifest(helmConfigMediaType, newArchive(t, "a")), "registry.example.com/charts/test-chart:0.1.0"),
+					withRefName(l.chartManifest(helmConfigMediaType, newArchive(t, "b")), "registry.example.com/other/test-chart:0.1.0"),
+				), "registry.example.com/other/test-chart:0.1.0"
+			},
+			expOut: "---\n# Source: test-chart/templates/cm.yaml\nname: b\n",
+		},
+
+		"A full reference that only matches the layout full references suffix should fail.": {
+			layout: func(t *testing.T) (fstest.MapFS, string) {
+				l := newTestOCILayout(t)
+				return l.index(withRefName(l.chartManifest(helmConfigMediaType, newArchive(t, "a")), "registry.example.com/charts/test-chart:0.1.0")), "other.example.com/charts/test-chart:0.1.0"
+			},
+			expErr: true,
+		},
+
+		"A tag that matches multiple manifests should fail.": {
+			layout: func(t *testing.T) (fstest.MapFS, string) {
+				l := newTestOCILayout(t)
+				return l.index(
+					withRefName(l.chartManifest(helmConfigMediaType, newArchive(t, "a")), "registry.example.com/charts/test-chart:0.1.0"),
+					withRefName(l.chartManifest(helmConfigMediaType, newArchive(t, "b")), "registry.example.com/other/test-chart:0.1.0"),
+				), "0.1.0"
+			},
+			expErr: true,
+		},
+
+		"A chart should be loaded by its manifest digest.": {
+			layout: func(t *testing.T) (fstest.MapFS, string) {
+				l := newTestOCILayout(t)
+				m := l.chartManifest(helmConfigMediaType, newArchive(t, "a"))
+				return l.index(withRefName(l.chartManifest(helmConfigMediaType, newArchive(t, "b")), "0.1.0"), m), m.Digest.String()
+			},
+			expOut: "---\n# Source: test-chart/templates/cm.yaml\nname: a\n",
+		},
+
+		"A chart should be loaded from a nested image index.": {
+			layout: func(t *testing.T) (fstest.MapFS, string) {
+				l := newTestOCILayout(t)
+				nested := l.jsonBlob(ocispec.MediaTypeImageIndex, ocispec.Index{
+					MediaType: ocispec.MediaTypeImageIndex,
+					Manifests: []ocispec.Descriptor{
+						l.chartManifest("application/vnd.oci.image.config.v1+json", newArchive(t, "image")),
+						l.chartManifest(helmConfigMediaType, newArchive(t, "a")),
+					},
+				})
+				return l.index(withRefName(nested, "0.1.0")), "0.1.0"
+			},
+			expOut: "---\n# Source: test-chart/templates/cm.yaml\nname: a\n",
+		},
+
+		"A missing reference should fail.": {
+			layout: func(t *testing.T) (fstest.MapFS, string) {
+				l := newTestOCILayout(t)
+				return l.index(withRefName(l.chartManifest(helmConfigMediaType, newArchive(t, "a")), "0.1.0")), "0.2.0"
+			},
+			expErr: true,
+		},
+
+		"A reference that is not a Helm chart should fail.": {
+			layout: func(t *testing.T) (fstest.MapFS, string) {
+				l := newTestOCILayout(t)
+				return l.index(withRefName(l.chartManifest("application/vnd.oci.image.config.v1+json", newArchive(t, "a")), "0.1.0")), "0.1.0"
+			},
+			expErr: true,
+		},
+
+		"A chart layer with a digest mismatch should fail.": {
+			layout: func(t *testing.T) (fstest.MapFS, string) {
+				l := newTestOCILayout(t)
+				m := l.chartManifest(helmConfigMediaType, newArchive(t, "a"))
+				tampered := newArchive(t, "b")
+				l.fs["blobs/sha256/"+digest.FromBytes(newArchive(t, "a")).Encoded()] = &fstest.MapFile{Data: tampered}
+				return l.index(withRefName(m, "0.1.0")), "0.1.0"
+			},
+			expErr: true,
+		},
+
+		"A directory that is not an OCI image layout should fail.": {
+			layout: func(t *testing.T) (fstest.MapFS, string) {
+				l := newTestOCILayout(t)
+				layout := l.index(withRefName(l.chartManifest(helmConfigMediaType, newArchive(t, "a")), "0.1.0"))
+				delete(layout, ocispec.ImageLayoutFile)
+				return layout, "0.1.0"
+			},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			layout, reference := test.layout(t)
+			chart, err := helm.LoadChartOCILayout(context.TODO(), layout, reference, helm.LoadChartOCILayoutConfig{})
+			if test.expErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+
+			out, err := helm.Template(context.TODO(), helm.TemplateConfig{ReleaseName: "test", Chart: chart})
+			require.NoError(err)
+			assert.Equal(test.expOut, out)
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package helm
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"helm.sh/helm/v4/pkg/chart/common"
+	chartutilv2 "helm.sh/helm/v4/pkg/chart/v2/util"
+)
+
+// packageModTime is the modification time of all the packaged chart files, so the packages
+// are reproducible.
+var packageModTime = time.Unix(0, 0).UTC()
+
+// Package writes the chart as a Helm chart archive (`.tgz`), like `helm package` does, that can be
+// published on a Helm repository or loaded with `LoadChartArchive`.
+//
+// The archive has the same layout as the Helm ones: `Chart.yaml` and `Chart.lock` are marshalled from the
+// loaded metadata and the subcharts (directories or archives) are nested on the `charts` directory, so the
+// packaged chart has the same digest (`Chart.Digest`). The archive is reproducible: the files are inside the
+// chart name directory, `Chart.yaml` is the first one followed by the rest sorted by path, and all of them
+// have the same modification time (Unix epoch).
+func Package(ctx context.Context, chart *Chart, w io.Writer) error {
+	c, err := chart.chartV2()
+	if err != nil {
+		return err
+	}
+
+	err = c.Validate()
+	if err != nil {
+		return fmt.Errorf("invalid chart: %w", err)
+	}
+
+	files := chartArchiveFiles(c, c.Name())
+	chartFile := path.Join(c.Name(), chartutilv2.ChartfileName)
+	slices.SortFunc(files, func(a, b *common.File) int {
+		// Chart.yaml first, like Helm.
+		switch {
+		case a.Name == chartFile:
+			return -1
+		case b.Name == chartFile:
+			return 1
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	gz := gzip.NewWriter(w)
+	gz.Comment = "Helm"
+	tw := tar.NewWriter(gz)
+
+	for _, f := range files {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("could not package chart: %w", err)
+		}
+
+		err := tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     f.Name,
+			Mode:     0o644,
+			Size:     int64(len(f.Data)),
+			ModTime:  packageModTime,
+		})
+		if err != nil {
+			return fmt.Errorf("could not write %s header: %w", f.Name, err)
+		}
+
+		_, err = tw.Write(f.Data)
+		if err != nil {
+			return fmt.Errorf("could not write %s: %w", f.Name, err)
+		}
+	}
+
+	err = tw.Close()
+	if err != nil {
+		return fmt.Errorf("could not close chart archive: %w", err)
+	}
+
+	err = gz.Close()
+	if err != nil {
+		return fmt.Errorf("could not compress chart archive: %w", err)
+	}
+
+	return nil
+}
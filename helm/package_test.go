@@ -0,0 +1,136 @@
+package helm_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/go-helm-template/helm"
+)
+
+func TestPackage(t *testing.T) {
+	tests := map[string]struct {
+		chart        func() fstest.MapFS
+		expFiles     []string
+		expChartYAML string
+		expOut       string
+	}{
+		"A chart should be packaged with Chart.yaml first and the files sorted inside the chart directory.": {
+			chart: func() fstest.MapFS {
+				chartFS := newTestChartFS()
+				chartFS["values.yaml"] = &fstest.MapFile{Data: []byte("name: test")}
+				chartFS["templates/cm.yaml"] = &fstest.MapFile{Data: []byte("name: {{ .Values.name }}")}
+				chartFS["templates/_helpers.tpl"] = &fstest.MapFile{Data: []byte(`{{- define "x" }}x{{ end }}`)}
+				chartFS["README.md"] = &fstest.MapFile{Data: []byte("readme")}
+				chartFS[".helmignore"] = &fstest.MapFile{Data: []byte("*.bak")}
+				chartFS["ignored.bak"] = &fstest.MapFile{Data: []byte("ignored")}
+				return chartFS
+			},
+			expFiles: []string{
+				"test-chart/Chart.yaml",
+				"test-chart/.helmignore",
+				"test-chart/README.md",
+				"test-chart/templates/_helpers.tpl",
+				"test-chart/templates/cm.yaml",
+				"test-chart/values.yaml",
+			},
+			expOut: "---\n# Source: test-chart/templates/cm.yaml\nname: test\n",
+		},
+
+		"A chart should be packaged with the Chart.yaml marshalled from the metadata, like Helm.": {
+			chart: func() fstest.MapFS {
+				chartFS := newTestChartFS()
+				chartFS["Chart.yaml"] = &fstest.MapFile{Data: []byte("# Test chart.\nversion: 0.1.0\nname: test-chart\napiVersion: v2\n")}
+				return chartFS
+			},
+			expFiles:     []string{"test-chart/Chart.yaml"},
+			expChartYAML: "apiVersion: v2\nname: test-chart\nversion: 0.1.0\n",
+		},
+
+		"A chart with subcharts archives should be packaged with the subcharts directories, like Helm.": {
+			chart: func() fstest.MapFS {
+				chartFS := newTestChartFS()
+				chartFS["charts/sub1-0.1.0.tgz"] = &fstest.MapFile{Data: newTestChartArchive(t, "sub1", []testArchiveFile{
+					{"Chart.yaml", "apiVersion: v2\nname: sub1\nversion: 0.1.0"},
+					{"templates/cm.yaml", "name: sub1"},
+				})}
+				return chartFS
+			},
+			expFiles: []string{
+				"test-chart/Chart.yaml",
+				"test-chart/charts/sub1/Chart.yaml",
+				"test-chart/charts/sub1/templates/cm.yaml",
+			},
+			expOut: "---\n# Source: test-chart/charts/sub1/templates/cm.yaml\nname: sub1\n",
+		},
+
+		"A chart with subcharts should be packaged with the subcharts.": {
+			chart: func() fstest.MapFS {
+				chartFS := newTestChartFS()
+				chartFS["charts/sub1/Chart.yaml"] = &fstest.MapFile{Data: []byte("apiVersion: v2\nname: sub1\nversion: 0.1.0")}
+				chartFS["charts/sub1/templates/cm.yaml"] = &fstest.MapFile{Data: []byte("name: sub1")}
+				return chartFS
+			},
+			expFiles: []string{
+				"test-chart/Chart.yaml",
+				"test-chart/charts/sub1/Chart.yaml",
+				"test-chart/charts/sub1/templates/cm.yaml",
+			},
+			expOut: "---\n# Source: test-chart/charts/sub1/templates/cm.yaml\nname: sub1\n",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			chart := mustLoadChart(test.chart())
+
+			var b1, b2 bytes.Buffer
+			require.NoError(helm.Package(context.TODO(), chart, &b1))
+			require.NoError(helm.Package(context.TODO(), chart, &b2))
+
+			// Packages should be reproducible.
+			assert.Equal(b1.Bytes(), b2.Bytes())
+
+			gz, err := gzip.NewReader(bytes.NewReader(b1.Bytes()))
+			require.NoError(err)
+			tr := tar.NewReader(gz)
+			gotFiles := []string{}
+			for {
+				h, err := tr.Next()
+				if err == io.EOF {
+					break
+				}
+				require.NoError(err)
+				gotFiles = append(gotFiles, h.Name)
+				assert.True(h.ModTime.Equal(time.Unix(0, 0)))
+
+				if h.Name == "test-chart/Chart.yaml" && test.expChartYAML != "" {
+					data, err := io.ReadAll(tr)
+					require.NoError(err)
+					assert.Equal(test.expChartYAML, string(data))
+				}
+			}
+			assert.Equal(test.expFiles, gotFiles)
+
+			// The packaged chart should be the same chart.
+			gotChart, err := helm.LoadChartArchive(context.TODO(), bytes.NewReader(b1.Bytes()), helm.LoadChartArchiveConfig{})
+			require.NoError(err)
+			assert.Equal(chart.Digest(), gotChart.Digest())
+
+			out, err := helm.Template(context.TODO(), helm.TemplateConfig{ReleaseName: "test", Chart: gotChart})
+			require.NoError(err)
+			assert.Equal(test.expOut, out)
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package helm
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"slices"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"helm.sh/helm/v4/pkg/provenance"
+)
+
+// ProvenanceConfig is the configuration used to verify a chart archive with its Helm provenance
+// file (`.prov`).
+type ProvenanceConfig struct {
+	// Provenance is the content of the chart archive provenance file.
+	Provenance []byte
+	// Keyring is the PGP public keyring (binary or armored) with the keys trusted to sign the chart.
+	Keyring []byte
+	// ArchiveName is the chart archive file name signed on the provenance file.
+	// By default `<chart name>-<chart version>.tgz` (as `helm package` does).
+	ArchiveName string
+}
+
+func (c *ProvenanceConfig) defaults() error {
+	if len(c.Provenance) == 0 {
+		return fmt.Errorf("provenance is required")
+	}
+
+	if len(c.Keyring) == 0 {
+		return fmt.Errorf("keyring is required")
+	}
+
+	return nil
+}
+
+// ProvenanceVerification is the result of a successful chart provenance verification.
+type ProvenanceVerification struct {
+	// SignedBy are the identities of the key that signed the chart (e.g: `Jane <jane@example.com>`).
+	SignedBy []string
+	// Fingerprint is the hex fingerprint of the key that signed the chart.
+	Fingerprint string
+	// FileName is the verified chart archive file name.
+	FileName string
+	// FileHash is the verified chart archive hash (e.g: `sha256:3f0a...`).
+	FileHash string
+}
+
+// verifyProvenance verifies the chart archive data against the provenance file signed with one of the
+// keyring keys.
+func verifyProvenance(archiveData []byte, archiveName string, config ProvenanceConfig) (*ProvenanceVerification, error) {
+	keyring, err := readKeyring(config.Keyring)
+	if err != nil {
+		return nil, fmt.Errorf("could not read keyring: %w", err)
+	}
+
+	signatory := provenance.Signatory{KeyRing: keyring}
+	v, err := signatory.Verify(archiveData, config.Provenance, archiveName)
+	if err != nil {
+		return nil, err
+	}
+
+	verification := &ProvenanceVerification{
+		FileName: v.FileName,
+		FileHash: v.FileHash,
+	}
+	if v.SignedBy != nil {
+		verification.Fingerprint = hex.EncodeToString(v.SignedBy.PrimaryKey.Fingerprint)
+		for name := range v.SignedBy.Identities {
+			verification.SignedBy = append(verification.SignedBy, name)
+		}
+		slices.Sort(verification.SignedBy)
+	}
+
+	return verification, nil
+}
+
+// readKeyring reads a binary or an armored PGP keyring.
+func readKeyring(data []byte) (openpgp.EntityList, error) {
+	keyring, err := openpgp.ReadKeyRing(bytes.NewReader(data))
+	if err == nil {
+		return keyring, nil
+	}
+
+	keyring, armoredErr := openpgp.ReadArmoredKeyRing(bytes.NewReader(data))
+	if armoredErr != nil {
+		return nil, err
+	}
+
+	return keyring, nil
+}
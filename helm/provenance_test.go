@@ -0,0 +1,170 @@
+package helm_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v4/pkg/provenance"
+
+	"github.com/slok/go-helm-template/helm"
+)
+
+func newTestPGPEntity(t *testing.T, name string) *openpgp.Entity {
+	e, err := openpgp.NewEntity(name, "", name+"@example.com", &packet.Config{Algorithm: packet.PubKeyAlgoEdDSA})
+	require.NoError(t, err)
+	return e
+}
+
+func testPGPKeyring(t *testing.T, e *openpgp.Entity, armored bool) []byte {
+	var b bytes.Buffer
+	if !armored {
+		require.NoError(t, e.Serialize(&b))
+		return b.Bytes()
+	}
+
+	w, err := armor.Encode(&b, openpgp.PublicKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, e.Serialize(w))
+	require.NoError(t, w.Close())
+	return b.Bytes()
+}
+
+func testProvenance(t *testing.T, e *openpgp.Entity, archive []byte, archiveName string) []byte {
+	s := provenance.Signatory{Entity: e}
+	prov, err := s.ClearSign(archive, archiveName, []byte("apiVersion: v2\nname: test-chart\nversion: 0.1.0\n"))
+	require.NoError(t, err)
+	return []byte(prov)
+}
+
+func TestLoadChartArchiveProvenance(t *testing.T) {
+	signer := newTestPGPEntity(t, "signer")
+	other := newTestPGPEntity(t, "other")
+	archive := newTestChartArchive(t, "test-chart", []testArchiveFile{
+		{"Chart.yaml", "apiVersion: v2\nname: test-chart\nversion: 0.1.0"},
+		{"templates/cm.yaml", "name: test"},
+	})
+	tamperedArchive := newTestChartArchive(t, "test-chart", []testArchiveFile{
+		{"Chart.yaml", "apiVersion: v2\nname: test-chart\nversion: 0.1.0"},
+		{"templates/cm.yaml", "name: tampered"},
+	})
+
+	tests := map[string]struct {
+		archive       []byte
+		provenance    func(t *testing.T) *helm.ProvenanceConfig
+		expProvenance *helm.ProvenanceVerification
+		expErr        bool
+	}{
+		"Loading an archive without provenance should not verify it.": {
+			archive: archive,
+		},
+
+		"Loading an archive with a valid provenance should verify it.": {
+			archive: archive,
+			provenance: func(t *testing.T) *helm.ProvenanceConfig {
+				return &helm.ProvenanceConfig{
+					Provenance: testProvenance(t, signer, archive, "test-chart-0.1.0.tgz"),
+					Keyring:    testPGPKeyring(t, signer, false),
+				}
+			},
+			expProvenance: &helm.ProvenanceVerification{
+				SignedBy:    []string{"signer <signer@example.com>"},
+				Fingerprint: hex.EncodeToString(signer.PrimaryKey.Fingerprint),
+				FileName:    "test-chart-0.1.0.tgz",
+				FileHash:    "sha256:" + mustDigest(t, archive),
+			},
+		},
+
+		"Loading an archive with a valid provenance and an armored keyring should verify it.": {
+			archive: archive,
+			provenance: func(t *testing.T) *helm.ProvenanceConfig {
+				return &helm.ProvenanceConfig{
+					Provenance:  testProvenance(t, signer, archive, "custom.tgz"),
+					Keyring:     testPGPKeyring(t, signer, true),
+					ArchiveName: "custom.tgz",
+				}
+			},
+			expProvenance: &helm.ProvenanceVerification{
+				SignedBy:    []string{"signer <signer@example.com>"},
+				Fingerprint: hex.EncodeToString(signer.PrimaryKey.Fingerprint),
+				FileName:    "custom.tgz",
+				FileHash:    "sha256:" + mustDigest(t, archive),
+			},
+		},
+
+		"Loading a tampered archive should fail.": {
+			archive: tamperedArchive,
+			provenance: func(t *testing.T) *helm.ProvenanceConfig {
+				return &helm.ProvenanceConfig{
+					Provenance: testProvenance(t, signer, archive, "test-chart-0.1.0.tgz"),
+					Keyring:    testPGPKeyring(t, signer, false),
+				}
+			},
+			expErr: true,
+		},
+
+		"Loading an archive signed with an untrusted key should fail.": {
+			archive: archive,
+			provenance: func(t *testing.T) *helm.ProvenanceConfig {
+				return &helm.ProvenanceConfig{
+					Provenance: testProvenance(t, other, archive, "test-chart-0.1.0.tgz"),
+					Keyring:    testPGPKeyring(t, signer, false),
+				}
+			},
+			expErr: true,
+		},
+
+		"Loading an archive with a provenance of another archive name should fail.": {
+			archive: archive,
+			provenance: func(t *testing.T) *helm.ProvenanceConfig {
+				return &helm.ProvenanceConfig{
+					Provenance: testProvenance(t, signer, archive, "other-0.1.0.tgz"),
+					Keyring:    testPGPKeyring(t, signer, false),
+				}
+			},
+			expErr: true,
+		},
+
+		"Loading an archive with a provenance without keyring should fail.": {
+			archive: archive,
+			provenance: func(t *testing.T) *helm.ProvenanceConfig {
+				return &helm.ProvenanceConfig{
+					Provenance: testProvenance(t, signer, archive, "test-chart-0.1.0.tgz"),
+				}
+			},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			config := helm.LoadChartArchiveConfig{}
+			if test.provenance != nil {
+				config.Provenance = test.provenance(t)
+			}
+
+			chart, err := helm.LoadChartArchive(context.TODO(), bytes.NewReader(test.archive), config)
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				require.NotNil(chart)
+				assert.Equal(test.expProvenance, chart.Provenance())
+			}
+		})
+	}
+}
+
+func mustDigest(t *testing.T, data []byte) string {
+	d, err := provenance.Digest(bytes.NewReader(data))
+	require.NoError(t, err)
+	return d
+}
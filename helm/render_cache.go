@@ -0,0 +1,376 @@
+package helm
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"text/template/parse"
+
+	chartv2 "helm.sh/helm/v4/pkg/chart/v2"
+)
+
+const (
+	defaultMemoryRenderCacheMaxEntries = 100
+
+	// renderCacheMaxCharts is the maximum number of charts with the non deterministic functions
+	// check memoized.
+	renderCacheMaxCharts = 100
+
+	// renderCacheKeyVersion is the version of the render cache keys and stored results, it needs
+	// to be changed when they are not compatible anymore.
+	renderCacheKeyVersion = "v1"
+)
+
+// NonDeterministicFuncs are the template functions that can return a different result on each
+// execution, the renders of charts using them are not cached.
+var NonDeterministicFuncs = []string{
+	"now",
+	"ago",
+	"randAlphaNum",
+	"randAlpha",
+	"randAscii",
+	"randNumeric",
+	"randBytes",
+	"randInt",
+	"shuffle",
+	"uuidv4",
+	"genPrivateKey",
+	"genCA",
+	"genCAWithKey",
+	"genSelfSignedCert",
+	"genSelfSignedCertWithKey",
+	"genSignedCert",
+	"genSignedCertWithKey",
+	"htpasswd",
+	"bcrypt",
+	"encryptAES",
+	"getHostByName",
+}
+
+// RenderCacheStorage stores the cached render results.
+type RenderCacheStorage interface {
+	// Get returns the stored data of the key, false if missing.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores the data of the key.
+	Set(ctx context.Context, key string, data []byte) error
+}
+
+// RenderCacheConfig is the configuration of the render cache.
+type RenderCacheConfig struct {
+	// Storage is where the render results are stored.
+	// By default an in memory storage of 100 renders.
+	Storage RenderCacheStorage
+}
+
+func (c *RenderCacheConfig) defaults() error {
+	if c.Storage == nil {
+		c.Storage = NewMemoryRenderCacheStorage(defaultMemoryRenderCacheMaxEntries)
+	}
+
+	return nil
+}
+
+// RenderCache caches the render results of the charts, the renders are identified by the chart
+// content digest, the values and the release settings.
+//
+// The renders are not cached when:
+//   - The chart templates use non deterministic functions (`NonDeterministicFuncs`).
+//   - Custom template functions are used.
+//   - The templates coverage or the manifests validation are enabled.
+//   - The values can't be represented as JSON.
+//
+// Note: The values rendered with `tpl` are not checked for non deterministic functions.
+type RenderCache struct {
+	storage RenderCacheStorage
+
+	// deterministic memoizes the non deterministic functions check by chart digest.
+	deterministic *MemoryRenderCacheStorage
+}
+
+// NewRenderCache returns a new render cache.
+func NewRenderCache(config RenderCacheConfig) (*RenderCache, error) {
+	err := config.defaults()
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return &RenderCache{
+		storage:       config.Storage,
+		deterministic: NewMemoryRenderCacheStorage(renderCacheMaxCharts),
+	}, nil
+}
+
+// renderCacheKey is the data that identifies a render.
+type renderCacheKey struct {
+	Version              string
+	Chart                string
+	Values               json.RawMessage
+	ReleaseName          string
+	Namespace            string
+	IsUpgrade            bool
+	Revision             int
+	ReleaseService       string
+	IncludeCRDs          bool
+	ShowFiles            []string
+	EnableHooks          bool
+	IncludeSubchartNotes bool
+	ValuesCoverage       bool
+	MissingValues        MissingValuesMode
+	Sandbox              *SandboxConfig
+}
+
+// key returns the cache key of the render, false if the render can't be cached.
+func (r *RenderCache) key(ctx context.Context, chart *chartv2.Chart, config TemplateConfig) (string, bool, error) {
+	if len(config.Funcs) > 0 || config.Coverage != nil || config.Validation != nil {
+		return "", false, nil
+	}
+
+	digest := chartDigest(chart)
+	deterministic, err := r.isDeterministic(ctx, digest, chart)
+	if err != nil {
+		return "", false, err
+	}
+	if !deterministic {
+		return "", false, nil
+	}
+
+	// JSON map keys are sorted, so this is canonical.
+	values, err := json.Marshal(config.Values)
+	if err != nil {
+		return "", false, nil
+	}
+
+	key, err := json.Marshal(renderCacheKey{
+		Version:              renderCacheKeyVersion,
+		Chart:                digest,
+		Values:               values,
+		ReleaseName:          config.ReleaseName,
+		Namespace:            config.Namespace,
+		IsUpgrade:            config.IsUpgrade,
+		Revision:             config.Revision,
+		ReleaseService:       config.ReleaseService,
+		IncludeCRDs:          config.IncludeCRDs,
+		ShowFiles:            config.ShowFiles,
+		EnableHooks:          config.EnableHooks,
+		IncludeSubchartNotes: config.IncludeSubchartNotes,
+		ValuesCoverage:       config.ValuesCoverage,
+		MissingValues:        config.MissingValues,
+		Sandbox:              config.Sandbox,
+	})
+	if err != nil {
+		return "", false, fmt.Errorf("could not marshal render cache key: %w", err)
+	}
+
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:]), true, nil
+}
+
+// isDeterministic returns true if the chart templates don't use non deterministic functions, it's
+// memoized by chart digest.
+func (r *RenderCache) isDeterministic(ctx context.Context, digest string, chart *chartv2.Chart) (bool, error) {
+	// The memory storage never fails.
+	data, ok, _ := r.deterministic.Get(ctx, digest)
+	if ok {
+		return len(data) > 0, nil
+	}
+
+	nonDeterministic := map[string]bool{}
+	for _, f := range NonDeterministicFuncs {
+		nonDeterministic[f] = true
+	}
+
+	deterministic := true
+	for _, t := range chartTemplates(chart) {
+		trees, err := parseTemplate(t.Name, t.File.Data)
+		if err != nil {
+			return false, fmt.Errorf("could not parse template %q: %w", t.Name, err)
+		}
+
+		for _, tree := range trees {
+			walkNode(tree.Root, func(n parse.Node) bool {
+				if id, ok := n.(*parse.IdentifierNode); ok && nonDeterministic[id.Ident] {
+					deterministic = false
+				}
+				return deterministic
+			})
+		}
+	}
+
+	// Deterministic charts are stored with data, so the check result is the data length.
+	data = nil
+	if deterministic {
+		data = []byte{1}
+	}
+	_ = r.deterministic.Set(ctx, digest, data)
+
+	return deterministic, nil
+}
+
+func (r *RenderCache) get(ctx context.Context, key string) (*RenderResult, bool, error) {
+	data, ok, err := r.storage.Get(ctx, key)
+	if err != nil {
+		return nil, false, fmt.Errorf("could not get cached render: %w", err)
+	}
+	if !ok {
+		return nil, false, nil
+	}
+
+	result := &RenderResult{}
+	err = json.Unmarshal(data, result)
+	if err != nil {
+		return nil, false, fmt.Errorf("could not unmarshal cached render: %w", err)
+	}
+
+	return result, true, nil
+}
+
+func (r *RenderCache) set(ctx context.Context, key string, result *RenderResult) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("could not marshal render: %w", err)
+	}
+
+	err = r.storage.Set(ctx, key, data)
+	if err != nil {
+		return fmt.Errorf("could not cache render: %w", err)
+	}
+
+	return nil
+}
+
+// MemoryRenderCacheStorage is a render cache storage that stores the renders in memory, when the
+// storage is full the least recently used render is evicted.
+type MemoryRenderCacheStorage struct {
+	maxEntries int
+	mu         sync.Mutex
+	lru        *list.List
+	entries    map[string]*list.Element
+}
+
+type memoryRenderCacheEntry struct {
+	key  string
+	data []byte
+}
+
+var _ RenderCacheStorage = &MemoryRenderCacheStorage{}
+
+// NewMemoryRenderCacheStorage returns a new in memory render cache storage with a maximum
+// number of stored renders.
+func NewMemoryRenderCacheStorage(maxEntries int) *MemoryRenderCacheStorage {
+	if maxEntries <= 0 {
+		maxEntries = defaultMemoryRenderCacheMaxEntries
+	}
+
+	return &MemoryRenderCacheStorage{
+		maxEntries: maxEntries,
+		lru:        list.New(),
+		entries:    map[string]*list.Element{},
+	}
+}
+
+// Get satisfies RenderCacheStorage interface.
+func (m *MemoryRenderCacheStorage) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	m.lru.MoveToFront(e)
+
+	return e.Value.(*memoryRenderCacheEntry).data, true, nil
+}
+
+// Set satisfies RenderCacheStorage interface.
+func (m *MemoryRenderCacheStorage) Set(ctx context.Context, key string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if e, ok := m.entries[key]; ok {
+		e.Value.(*memoryRenderCacheEntry).data = data
+		m.lru.MoveToFront(e)
+		return nil
+	}
+
+	m.entries[key] = m.lru.PushFront(&memoryRenderCacheEntry{key: key, data: data})
+	for m.lru.Len() > m.maxEntries {
+		oldest := m.lru.Back()
+		m.lru.Remove(oldest)
+		delete(m.entries, oldest.Value.(*memoryRenderCacheEntry).key)
+	}
+
+	return nil
+}
+
+// Len returns the number of stored renders.
+func (m *MemoryRenderCacheStorage) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.lru.Len()
+}
+
+// DirRenderCacheStorage is a render cache storage that stores the renders as files on a directory,
+// the stored renders are never evicted.
+type DirRenderCacheStorage struct {
+	dir string
+}
+
+var _ RenderCacheStorage = &DirRenderCacheStorage{}
+
+// NewDirRenderCacheStorage returns a new render cache storage on a directory, the directory is
+// created if missing.
+func NewDirRenderCacheStorage(dir string) (*DirRenderCacheStorage, error) {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, fmt.Errorf("could not create cache directory: %w", err)
+	}
+
+	return &DirRenderCacheStorage{dir: dir}, nil
+}
+
+// Get satisfies RenderCacheStorage interface.
+func (d *DirRenderCacheStorage) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	data, err := os.ReadFile(d.path(key))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+
+	return data, true, nil
+}
+
+// Set satisfies RenderCacheStorage interface.
+func (d *DirRenderCacheStorage) Set(ctx context.Context, key string, data []byte) error {
+	// Write atomically, so concurrent readers never read partial renders.
+	tmp, err := os.CreateTemp(d.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), d.path(key))
+}
+
+func (d *DirRenderCacheStorage) path(key string) string {
+	return filepath.Join(d.dir, key+".json")
+}
@@ -0,0 +1,177 @@
+package helm_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"text/template"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/go-helm-template/helm"
+)
+
+type testRenderCacheStorage struct {
+	helm.RenderCacheStorage
+	hits int
+	sets int
+}
+
+func (t *testRenderCacheStorage) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	data, ok, err := t.RenderCacheStorage.Get(ctx, key)
+	if ok {
+		t.hits++
+	}
+	return data, ok, err
+}
+
+func (t *testRenderCacheStorage) Set(ctx context.Context, key string, data []byte) error {
+	t.sets++
+	return t.RenderCacheStorage.Set(ctx, key, data)
+}
+
+func TestRenderCache(t *testing.T) {
+	newChart := func(tpl string) *helm.Chart {
+		chartFS := newTestChartFS()
+		chartFS["templates/cm.yaml"] = &fstest.MapFile{Data: []byte(tpl)}
+		chartFS["templates/NOTES.txt"] = &fstest.MapFile{Data: []byte("Installed {{ .Release.Name }}")}
+		return mustLoadChart(chartFS)
+	}
+
+	tests := map[string]struct {
+		storage     func(t *testing.T) helm.RenderCacheStorage
+		configs     []helm.TemplateConfig
+		expSets     int
+		expHits     int
+		expNotes    string
+		expWarnLogs int
+	}{
+		"Identical renders should be cached.": {
+			configs: []helm.TemplateConfig{
+				{ReleaseName: "test", Chart: newChart("name: {{ .Values.name }}"), Values: map[string]interface{}{"name": "a", "b": 1}},
+				{ReleaseName: "test", Chart: newChart("name: {{ .Values.name }}"), Values: map[string]interface{}{"b": 1, "name": "a"}},
+			},
+			expSets:  1,
+			expHits:  1,
+			expNotes: "Installed test",
+		},
+
+		"Renders with different values or release settings should not share the cache.": {
+			configs: []helm.TemplateConfig{
+				{ReleaseName: "test", Chart: newChart("name: {{ .Values.name }}"), Values: map[string]interface{}{"name": "a"}},
+				{ReleaseName: "test", Chart: newChart("name: {{ .Values.name }}"), Values: map[string]interface{}{"name": "b"}},
+				{ReleaseName: "test2", Chart: newChart("name: {{ .Values.name }}"), Values: map[string]interface{}{"name": "b"}},
+				{ReleaseName: "test2", Chart: newChart("name: {{ .Values.name }}"), Values: map[string]interface{}{"name": "b"}, IsUpgrade: true},
+				{ReleaseName: "test2", Chart: newChart("name: {{ .Values.name }}"), Values: map[string]interface{}{"name": "b"}, IsUpgrade: true},
+			},
+			expSets:  4,
+			expHits:  1,
+			expNotes: "Installed test2",
+		},
+
+		"Renders of charts with non deterministic functions should not be cached.": {
+			configs: []helm.TemplateConfig{
+				{ReleaseName: "test", Chart: newChart("name: {{ randAlphaNum 10 }}")},
+				{ReleaseName: "test", Chart: newChart("name: {{ randAlphaNum 10 }}")},
+			},
+			expNotes: "Installed test",
+		},
+
+		"Renders with custom functions should not be cached.": {
+			configs: []helm.TemplateConfig{
+				{ReleaseName: "test", Chart: newChart("name: {{ custom }}"), Funcs: template.FuncMap{"custom": func() string { return "a" }}},
+				{ReleaseName: "test", Chart: newChart("name: {{ custom }}"), Funcs: template.FuncMap{"custom": func() string { return "a" }}},
+			},
+			expNotes: "Installed test",
+		},
+
+		"Cached renders should log the render warnings.": {
+			configs: []helm.TemplateConfig{
+				{ReleaseName: "test", Chart: newTestWarningsChart()},
+				{ReleaseName: "test", Chart: newTestWarningsChart()},
+			},
+			expSets:     1,
+			expHits:     1,
+			expWarnLogs: 2,
+		},
+
+		"Renders should be cached on a directory.": {
+			storage: func(t *testing.T) helm.RenderCacheStorage {
+				s, err := helm.NewDirRenderCacheStorage(t.TempDir())
+				require.NoError(t, err)
+				return s
+			},
+			configs: []helm.TemplateConfig{
+				{ReleaseName: "test", Chart: newChart("name: {{ .Values.name }}"), Values: map[string]interface{}{"name": "a"}},
+				{ReleaseName: "test", Chart: newChart("name: {{ .Values.name }}"), Values: map[string]interface{}{"name": "a"}},
+			},
+			expSets:  1,
+			expHits:  1,
+			expNotes: "Installed test",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			var storage helm.RenderCacheStorage = helm.NewMemoryRenderCacheStorage(10)
+			if test.storage != nil {
+				storage = test.storage(t)
+			}
+			testStorage := &testRenderCacheStorage{RenderCacheStorage: storage}
+			cache, err := helm.NewRenderCache(helm.RenderCacheConfig{Storage: testStorage})
+			require.NoError(err)
+
+			var logs bytes.Buffer
+			logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+			var last *helm.RenderResult
+			for _, config := range test.configs {
+				uncachedResult, err := helm.Render(context.TODO(), config)
+				require.NoError(err)
+
+				config.RenderCache = cache
+				config.Logger = logger
+				last, err = helm.Render(context.TODO(), config)
+				require.NoError(err)
+
+				// The not cached renders can be non deterministic.
+				if test.expSets > 0 {
+					assert.Equal(uncachedResult, last)
+				}
+			}
+
+			assert.Equal(test.expSets, testStorage.sets)
+			assert.Equal(test.expHits, testStorage.hits)
+			assert.Equal(test.expNotes, last.Notes)
+			assert.Equal(test.expWarnLogs, strings.Count(logs.String(), "level=WARN"))
+		})
+	}
+}
+
+func TestDirRenderCacheStorage(t *testing.T) {
+	dir := t.TempDir() + "/cache"
+	storage, err := helm.NewDirRenderCacheStorage(dir)
+	require.NoError(t, err)
+
+	_, ok, err := storage.Get(context.TODO(), "test")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, storage.Set(context.TODO(), "test", []byte("data")))
+	data, ok, err := storage.Get(context.TODO(), "test")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("data"), data)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
@@ -0,0 +1,207 @@
+package helm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"go.opentelemetry.io/otel/trace"
+	"sigs.k8s.io/yaml"
+)
+
+const repoIndexFile = "index.yaml"
+
+// repoIndex is the Helm repository index, only with the fields required to load the charts.
+type repoIndex struct {
+	Entries map[string][]*repoChartVersion `json:"entries"`
+}
+
+// repoChartVersion is a chart version of the Helm repository index.
+type repoChartVersion struct {
+	Name    string   `json:"name"`
+	Version string   `json:"version"`
+	URLs    []string `json:"urls"`
+	Digest  string   `json:"digest"`
+	Removed bool     `json:"removed"`
+}
+
+// LoadChartFromRepoConfig is the configuration used to load a chart from a Helm repository.
+type LoadChartFromRepoConfig struct {
+	// Logger when set will receive the debug events of the chart loading and the Helm internal
+	// logs, instead of writing them to stderr (see `LoadChartConfig.Logger`).
+	Logger *slog.Logger
+	// TracerProvider when set will be used to trace the chart loading with OpenTelemetry.
+	TracerProvider trace.TracerProvider
+	// Metrics when set will record the chart loading metrics.
+	Metrics MetricsRecorder
+	// Prereleases includes the prerelease versions (e.g: `1.0.0-rc.1`) when selecting the chart version.
+	Prereleases bool
+	// Keyring when set will verify the chart archive with its provenance file (the chart archive path
+	// with the `.prov` suffix) using the PGP keyring (binary or armored).
+	Keyring []byte
+}
+
+// LoadChartFromRepo loads a chart from a Helm repository directory (an `index.yaml` and the chart archives,
+// like the ones created with `helm repo index`), the fs.FS root must be the repository root.
+//
+// The selected chart version is the highest one that matches the version constraint (e.g: `1.2.3`, `~1.2`, `>=1.0.0 <2.0.0`),
+// an empty constraint matches any version. The chart archive digest is verified against the index one.
+func LoadChartFromRepo(ctx context.Context, repoFS fs.FS, name, versionConstraint string) (*Chart, error) {
+	return LoadChartFromRepoWithConfig(ctx, repoFS, name, versionConstraint, LoadChartFromRepoConfig{})
+}
+
+// LoadChartFromRepoWithConfig is the same as LoadChartFromRepo but with a custom configuration.
+func LoadChartFromRepoWithConfig(ctx context.Context, repoFS fs.FS, name, versionConstraint string, config LoadChartFromRepoConfig) (chart *Chart, err error) {
+	ctx, span := startSpan(ctx, config.TracerProvider, "LoadChartFromRepo")
+	defer func() { endSpan(span, err) }()
+
+	start := time.Now()
+	defer func() { observeChartLoad(ctx, config.Metrics, start, chart, err) }()
+
+	logger := loggerOrDiscard(config.Logger)
+
+	index, err := readRepoIndex(repoFS)
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := selectRepoChartVersion(index, name, versionConstraint, config.Prereleases)
+	if err != nil {
+		return nil, err
+	}
+	logger.DebugContext(ctx, "chart version selected", "chart", name, "constraint", versionConstraint, "version", version.Version)
+
+	archivePath, data, err := readRepoChartArchive(repoFS, version)
+	if err != nil {
+		return nil, err
+	}
+
+	var prov *ProvenanceConfig
+	if len(config.Keyring) > 0 {
+		provData, err := fs.ReadFile(repoFS, archivePath+".prov")
+		if err != nil {
+			return nil, fmt.Errorf("could not read chart provenance: %w", err)
+		}
+		prov = &ProvenanceConfig{
+			Provenance:  provData,
+			Keyring:     config.Keyring,
+			ArchiveName: path.Base(archivePath),
+		}
+	}
+
+	return loadChartArchive(ctx, data, prov, logger)
+}
+
+// readRepoIndex reads the Helm repository index.
+func readRepoIndex(repoFS fs.FS) (*repoIndex, error) {
+	data, err := fs.ReadFile(repoFS, repoIndexFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not read repository index: %w", err)
+	}
+
+	index := &repoIndex{}
+	err = yaml.Unmarshal(data, index)
+	if err != nil {
+		return nil, fmt.Errorf("could not unmarshal repository index: %w", err)
+	}
+
+	return index, nil
+}
+
+// selectRepoChartVersion returns the highest chart version of the index that matches the constraint.
+func selectRepoChartVersion(index *repoIndex, name, versionConstraint string, prereleases bool) (*repoChartVersion, error) {
+	if versionConstraint == "" {
+		versionConstraint = "*"
+	}
+
+	constraint, err := semver.NewConstraint(versionConstraint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid version constraint %q: %w", versionConstraint, err)
+	}
+	constraint.IncludePrerelease = prereleases
+
+	var (
+		selected        *repoChartVersion
+		selectedVersion *semver.Version
+	)
+	for _, cv := range index.Entries[name] {
+		if cv == nil || cv.Removed {
+			continue
+		}
+
+		v, err := semver.NewVersion(cv.Version)
+		if err != nil || !constraint.Check(v) {
+			continue
+		}
+
+		if selectedVersion == nil || v.GreaterThan(selectedVersion) {
+			selected, selectedVersion = cv, v
+		}
+	}
+
+	if selected == nil {
+		return nil, fmt.Errorf("no %q chart version on the repository matches %q", name, versionConstraint)
+	}
+
+	return selected, nil
+}
+
+// readRepoChartArchive reads the chart version archive from the repository verifying its digest, it
+// returns the archive path on the repository and its data.
+func readRepoChartArchive(repoFS fs.FS, version *repoChartVersion) (string, []byte, error) {
+	if version.Digest == "" {
+		return "", nil, fmt.Errorf("chart %s-%s has no digest on the repository index", version.Name, version.Version)
+	}
+
+	for _, u := range version.URLs {
+		archivePath, err := repoChartArchivePath(u)
+		if err != nil {
+			return "", nil, err
+		}
+
+		data, err := fs.ReadFile(repoFS, archivePath)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("could not read chart archive: %w", err)
+		}
+
+		sum := sha256.Sum256(data)
+		if got := hex.EncodeToString(sum[:]); got != version.Digest {
+			return "", nil, fmt.Errorf("chart archive %s digest mismatch: expected %s, got %s", archivePath, version.Digest, got)
+		}
+
+		return archivePath, data, nil
+	}
+
+	return "", nil, fmt.Errorf("chart %s-%s archive is missing on the repository", version.Name, version.Version)
+}
+
+// repoChartArchivePath returns the path on the repository of an index chart URL, the absolute URLs are
+// considered mirrored on the repository root (e.g: `https://charts.example.com/app-1.0.0.tgz` is `app-1.0.0.tgz`).
+func repoChartArchivePath(chartURL string) (string, error) {
+	u, err := url.Parse(chartURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid chart URL %q: %w", chartURL, err)
+	}
+
+	if u.IsAbs() {
+		return path.Base(u.Path), nil
+	}
+
+	p := path.Clean(u.Path)
+	if !fs.ValidPath(p) {
+		return "", fmt.Errorf("invalid chart URL %q, the chart archive is outside of the repository", chartURL)
+	}
+
+	return p, nil
+}
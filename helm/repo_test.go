@@ -0,0 +1,197 @@
+package helm_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/go-helm-template/helm"
+)
+
+type testRepoChart struct {
+	version  string
+	url      string
+	digest   string
+	noDigest bool
+}
+
+// newTestRepoFS returns a Helm repository with the charts archives and its index.
+func newTestRepoFS(t *testing.T, charts ...testRepoChart) fstest.MapFS {
+	repoFS := fstest.MapFS{}
+	var index strings.Builder
+	index.WriteString("apiVersion: v1\nentries:\n  test-chart:\n")
+	for _, c := range charts {
+		data := newTestChartArchive(t, "test-chart", []testArchiveFile{
+			{"Chart.yaml", "apiVersion: v2\nname: test-chart\nversion: " + c.version},
+			{"templates/cm.yaml", "version: {{ .Chart.Version }}"},
+		})
+		url := c.url
+		if url == "" {
+			url = "test-chart-" + c.version + ".tgz"
+		}
+		digest := c.digest
+		if digest == "" && !c.noDigest {
+			sum := sha256.Sum256(data)
+			digest = hex.EncodeToString(sum[:])
+		}
+
+		repoFS["test-chart-"+c.version+".tgz"] = &fstest.MapFile{Data: data}
+		fmt.Fprintf(&index, "  - apiVersion: v2\n    name: test-chart\n    version: %s\n    digest: %q\n    urls:\n    - %s\n", c.version, digest, url)
+	}
+	repoFS["index.yaml"] = &fstest.MapFile{Data: []byte(index.String())}
+
+	return repoFS
+}
+
+func TestLoadChartFromRepo(t *testing.T) {
+	signer := newTestPGPEntity(t, "signer")
+
+	defaultRepo := func(t *testing.T) fstest.MapFS {
+		return newTestRepoFS(t,
+			testRepoChart{version: "0.1.0"},
+			testRepoChart{version: "0.1.1"},
+			testRepoChart{version: "0.2.0"},
+			testRepoChart{version: "0.3.0-rc.1"},
+		)
+	}
+
+	tests := map[string]struct {
+		repo       func(t *testing.T) fstest.MapFS
+		name       string
+		constraint string
+		config     helm.LoadChartFromRepoConfig
+		expVersion string
+		expErr     bool
+	}{
+		"Without constraint the highest release version should be loaded.": {
+			repo:       defaultRepo,
+			name:       "test-chart",
+			expVersion: "0.2.0",
+		},
+
+		"The highest version matching the constraint should be loaded.": {
+			repo:       defaultRepo,
+			name:       "test-chart",
+			constraint: "~0.1",
+			expVersion: "0.1.1",
+		},
+
+		"An exact version should be loaded.": {
+			repo:       defaultRepo,
+			name:       "test-chart",
+			constraint: "0.1.0",
+			expVersion: "0.1.0",
+		},
+
+		"Prereleases should be loaded when enabled.": {
+			repo:       defaultRepo,
+			name:       "test-chart",
+			config:     helm.LoadChartFromRepoConfig{Prereleases: true},
+			expVersion: "0.3.0-rc.1",
+		},
+
+		"Prereleases should be loaded when the constraint has a prerelease.": {
+			repo:       defaultRepo,
+			name:       "test-chart",
+			constraint: ">=0.3.0-0",
+			expVersion: "0.3.0-rc.1",
+		},
+
+		"Charts with absolute URLs should be loaded from the repository root.": {
+			repo: func(t *testing.T) fstest.MapFS {
+				return newTestRepoFS(t, testRepoChart{version: "0.1.0", url: "https://charts.example.com/test-chart-0.1.0.tgz"})
+			},
+			name:       "test-chart",
+			expVersion: "0.1.0",
+		},
+
+		"Charts with provenance should be verified when a keyring is set.": {
+			repo: func(t *testing.T) fstest.MapFS {
+				repoFS := newTestRepoFS(t, testRepoChart{version: "0.1.0"})
+				repoFS["test-chart-0.1.0.tgz.prov"] = &fstest.MapFile{Data: testProvenance(t, signer, repoFS["test-chart-0.1.0.tgz"].Data, "test-chart-0.1.0.tgz")}
+				return repoFS
+			},
+			name:       "test-chart",
+			config:     helm.LoadChartFromRepoConfig{Keyring: testPGPKeyring(t, signer, false)},
+			expVersion: "0.1.0",
+		},
+
+		"Charts without provenance should fail when a keyring is set.": {
+			repo: func(t *testing.T) fstest.MapFS {
+				return newTestRepoFS(t, testRepoChart{version: "0.1.0"})
+			},
+			name:   "test-chart",
+			config: helm.LoadChartFromRepoConfig{Keyring: testPGPKeyring(t, signer, false)},
+			expErr: true,
+		},
+
+		"Charts with a digest mismatch should fail.": {
+			repo: func(t *testing.T) fstest.MapFS {
+				return newTestRepoFS(t, testRepoChart{version: "0.1.0", digest: strings.Repeat("0", 64)})
+			},
+			name:   "test-chart",
+			expErr: true,
+		},
+
+		"Charts without digest should fail.": {
+			repo: func(t *testing.T) fstest.MapFS {
+				return newTestRepoFS(t, testRepoChart{version: "0.1.0", noDigest: true})
+			},
+			name:   "test-chart",
+			expErr: true,
+		},
+
+		"Charts outside of the repository should fail.": {
+			repo: func(t *testing.T) fstest.MapFS {
+				return newTestRepoFS(t, testRepoChart{version: "0.1.0", url: "../test-chart-0.1.0.tgz"})
+			},
+			name:   "test-chart",
+			expErr: true,
+		},
+
+		"Missing chart versions should fail.": {
+			repo:       defaultRepo,
+			name:       "test-chart",
+			constraint: ">=1.0.0",
+			expErr:     true,
+		},
+
+		"Missing charts should fail.": {
+			repo:   defaultRepo,
+			name:   "other-chart",
+			expErr: true,
+		},
+
+		"Invalid constraints should fail.": {
+			repo:       defaultRepo,
+			name:       "test-chart",
+			constraint: "invalid",
+			expErr:     true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			chart, err := helm.LoadChartFromRepoWithConfig(context.TODO(), test.repo(t), test.name, test.constraint, test.config)
+			if test.expErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+
+			out, err := helm.Template(context.TODO(), helm.TemplateConfig{ReleaseName: "test", Chart: chart})
+			require.NoError(err)
+			assert.Equal("---\n# Source: test-chart/templates/cm.yaml\nversion: "+test.expVersion+"\n", out)
+		})
+	}
+}
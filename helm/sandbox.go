@@ -0,0 +1,399 @@
+package helm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+const (
+	defaultSandboxMaxOutputBytes  = 10 * 1024 * 1024
+	defaultSandboxMaxIncludeDepth = 100
+
+	sandboxTemplateFuncName = "goHelmTemplateSandboxTemplate"
+	sandboxEnterFuncName    = "goHelmTemplateSandboxEnter"
+	sandboxLeaveFuncName    = "goHelmTemplateSandboxLeave"
+	sandboxTextFuncName     = "goHelmTemplateSandboxText"
+	sandboxOutputFuncName   = "goHelmTemplateSandboxOutput"
+	sandboxDirectFuncName   = "goHelmTemplateSandboxDirect"
+	sandboxTplFuncName      = "goHelmTemplateSandboxTpl"
+)
+
+// DefaultSandboxForbiddenFuncs are the template functions that are forbidden by default
+// when rendering in a sandbox.
+var DefaultSandboxForbiddenFuncs = []string{
+	"env",
+	"expandenv",
+	"getHostByName",
+	"lookup",
+}
+
+// DefaultSandboxForbiddenFilesMethods are the `.Files` methods that are forbidden by default
+// when rendering in a sandbox.
+var DefaultSandboxForbiddenFilesMethods = []string{
+	"Glob",
+}
+
+// SandboxConfig is the configuration to render untrusted charts in a sandbox.
+type SandboxConfig struct {
+	// ForbiddenFuncs are the template functions that the chart templates are not allowed to use.
+	// By default `DefaultSandboxForbiddenFuncs`.
+	ForbiddenFuncs []string
+	// ForbiddenFilesMethods are the `.Files` methods (e.g: `Glob`, `Get`) that the chart templates are not
+	// allowed to use. The `.Files` object can be assigned to variables, so the methods are forbidden on any
+	// field access with the same name (e.g: `.Values.Glob`).
+	// By default `DefaultSandboxForbiddenFilesMethods`.
+	ForbiddenFilesMethods []string
+	// MaxOutputBytes is the maximum size of the rendered templates, the limit is enforced while
+	// the templates are executed. By default 10MiB.
+	MaxOutputBytes int
+	// MaxIncludeDepth is the maximum nesting depth of named templates execution (e.g: `include`, `template`).
+	// By default 100.
+	MaxIncludeDepth int
+}
+
+func (c *SandboxConfig) defaults() error {
+	if c.ForbiddenFuncs == nil {
+		c.ForbiddenFuncs = DefaultSandboxForbiddenFuncs
+	}
+
+	if c.ForbiddenFilesMethods == nil {
+		c.ForbiddenFilesMethods = DefaultSandboxForbiddenFilesMethods
+	}
+
+	if c.MaxOutputBytes < 0 {
+		return fmt.Errorf("max output bytes can't be negative")
+	}
+
+	if c.MaxOutputBytes == 0 {
+		c.MaxOutputBytes = defaultSandboxMaxOutputBytes
+	}
+
+	if c.MaxIncludeDepth < 0 {
+		return fmt.Errorf("max include depth can't be negative")
+	}
+
+	if c.MaxIncludeDepth == 0 {
+		c.MaxIncludeDepth = defaultSandboxMaxIncludeDepth
+	}
+
+	return nil
+}
+
+// SandboxPolicyError is the error returned when a chart breaks the sandbox policy.
+type SandboxPolicyError struct {
+	// Template is the template file or named template that broke the policy (if any).
+	Template string
+	// Func is the forbidden function or `.Files` method (e.g: `Files.Glob`) used (if any).
+	Func string
+	// Reason is the description of the policy that has been broken.
+	Reason string
+}
+
+func (e *SandboxPolicyError) Error() string {
+	msg := "sandbox policy violation"
+	if e.Template != "" {
+		msg += fmt.Sprintf(" on template %q", e.Template)
+	}
+	if e.Func != "" {
+		msg += fmt.Sprintf(": forbidden function %q", e.Func)
+	}
+	if e.Reason != "" {
+		msg += ": " + e.Reason
+	}
+
+	return msg
+}
+
+// sandbox knows how to prepare charts and template functions to be rendered in a sandbox.
+//
+// Helm engine doesn't let us set the writer of the templates execution, so the templates nodes that
+// write to the output are instrumented to account the written bytes.
+type sandbox struct {
+	config         SandboxConfig
+	forbidden      map[string]struct{}
+	forbiddenFiles map[string]struct{}
+	// frames are the named templates being executed, true when the named template writes directly
+	// to the output (e.g: `template`) instead of being captured (e.g: `include`).
+	frames []bool
+	// templates are the names of the named templates being executed and file the name of the
+	// template file being executed.
+	templates []string
+	file      string
+	direct    bool
+	written   int
+}
+
+func newSandbox(config SandboxConfig) *sandbox {
+	forbidden := map[string]struct{}{}
+	for _, f := range config.ForbiddenFuncs {
+		forbidden[f] = struct{}{}
+	}
+
+	forbiddenFiles := map[string]struct{}{}
+	for _, m := range config.ForbiddenFilesMethods {
+		forbiddenFiles[m] = struct{}{}
+	}
+
+	return &sandbox{
+		config:         config,
+		forbidden:      forbidden,
+		forbiddenFiles: forbiddenFiles,
+	}
+}
+
+// prepareTemplate checks the template doesn't use forbidden functions and instruments the
+// templates so the output size, the named templates nesting depth and the `tpl` templates
+// can be checked while executing.
+func (s *sandbox) prepareTemplate(name string, data []byte) ([]byte, error) {
+	trees, err := parseTemplate(name, data)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse template %q: %w", name, err)
+	}
+
+	err = s.checkTrees(name, trees)
+	if err != nil {
+		return nil, err
+	}
+
+	for n, t := range trees {
+		instrumentSandboxOutput(t.Root)
+		instrumentSandboxTpl(t.Root)
+		if n == name {
+			t.Root.Nodes = append([]parse.Node{sandboxFuncCallNode(sandboxTemplateFuncName, strconv.Quote(n))}, t.Root.Nodes...)
+			continue
+		}
+		t.Root.Nodes = append(append([]parse.Node{sandboxFuncCallNode(sandboxEnterFuncName, strconv.Quote(n))}, t.Root.Nodes...), sandboxFuncCallNode(sandboxLeaveFuncName))
+	}
+
+	return treesToSource(name, data, trees), nil
+}
+
+// checkTrees checks the template trees don't use forbidden functions or `.Files` methods.
+func (s *sandbox) checkTrees(name string, trees map[string]*parse.Tree) error {
+	var policyErr error
+	checkFields := func(fields []string) {
+		for _, f := range fields {
+			if _, ok := s.forbiddenFiles[f]; ok {
+				policyErr = &SandboxPolicyError{Template: name, Func: "Files." + f}
+				return
+			}
+		}
+	}
+
+	for _, t := range trees {
+		walkNode(t.Root, func(n parse.Node) bool {
+			switch n := n.(type) {
+			case *parse.IdentifierNode:
+				if _, ok := s.forbidden[n.Ident]; ok {
+					policyErr = &SandboxPolicyError{Template: name, Func: n.Ident}
+				}
+			case *parse.FieldNode:
+				checkFields(n.Ident)
+			case *parse.VariableNode:
+				checkFields(n.Ident[1:])
+			case *parse.ChainNode:
+				checkFields(n.Field)
+			}
+			return policyErr == nil
+		})
+		if policyErr != nil {
+			return policyErr
+		}
+	}
+
+	return nil
+}
+
+// instrumentSandboxTpl instruments the `tpl` calls so their templates are checked before being
+// executed, it returns true if any `tpl` call has been instrumented.
+func instrumentSandboxTpl(root *parse.ListNode) bool {
+	instrumented := false
+	walkNode(root, func(n parse.Node) bool {
+		c, ok := n.(*parse.CommandNode)
+		if !ok || len(c.Args) < 2 {
+			return true
+		}
+
+		if id, ok := c.Args[0].(*parse.IdentifierNode); !ok || id.Ident != "tpl" {
+			return true
+		}
+
+		check := sandboxFuncCallNode(sandboxTplFuncName, "(.)").(*parse.ActionNode)
+		check.Pipe.Cmds[0].Args[1] = c.Args[1]
+		c.Args[1] = check.Pipe
+		instrumented = true
+
+		return true
+	})
+
+	return instrumented
+}
+
+// instrumentSandboxOutput instruments the nodes that write to the output: the text, the actions
+// that print their result and the named templates executed with `template`.
+func instrumentSandboxOutput(root *parse.ListNode) {
+	walkNode(root, func(n parse.Node) bool {
+		l, ok := n.(*parse.ListNode)
+		if !ok || l == nil {
+			return true
+		}
+
+		nodes := make([]parse.Node, 0, len(l.Nodes))
+		for _, c := range l.Nodes {
+			switch c := c.(type) {
+			case *parse.TextNode:
+				nodes = append(nodes, sandboxFuncCallNode(sandboxTextFuncName, strconv.Itoa(len(c.Text))))
+			case *parse.ActionNode:
+				if len(c.Pipe.Decl) == 0 && !isInstrumentationNode(c) {
+					output := sandboxFuncCallNode(sandboxOutputFuncName).(*parse.ActionNode)
+					c.Pipe.Cmds = append(c.Pipe.Cmds, output.Pipe.Cmds...)
+				}
+			case *parse.TemplateNode:
+				// Mark the named template as direct after evaluating the pipeline, it could execute
+				// other named templates (e.g: `include`).
+				if c.Pipe == nil || len(c.Pipe.Decl) > 0 {
+					nodes = append(nodes, sandboxFuncCallNode(sandboxDirectFuncName))
+					break
+				}
+				direct := sandboxFuncCallNode(sandboxDirectFuncName, "(.)").(*parse.ActionNode)
+				direct.Pipe.Cmds[0].Args[1] = c.Pipe
+				c.Pipe = direct.Pipe
+			}
+			nodes = append(nodes, c)
+		}
+		l.Nodes = nodes
+
+		return true
+	})
+}
+
+func sandboxFuncCallNode(funcName string, args ...string) parse.Node {
+	src := strings.Join(append([]string{funcName}, args...), " ")
+	trees, err := parseTemplate(funcName, []byte("{{ "+src+" }}"))
+	if err != nil {
+		// Should never happen, it's a static template.
+		panic(err)
+	}
+	return trees[funcName].Root.Nodes[0]
+}
+
+// funcs returns the template functions that enforce the sandbox policy at execution time.
+func (s *sandbox) funcs() template.FuncMap {
+	funcs := template.FuncMap{
+		sandboxTemplateFuncName: func(name string) string {
+			s.file = name
+			return ""
+		},
+		sandboxEnterFuncName: func(name string) (string, error) {
+			s.frames = append(s.frames, s.direct)
+			s.templates = append(s.templates, name)
+			s.direct = false
+			if len(s.frames) > s.config.MaxIncludeDepth {
+				return "", &SandboxPolicyError{Template: name, Reason: fmt.Sprintf("max named templates nesting depth (%d) exceeded", s.config.MaxIncludeDepth)}
+			}
+			return "", nil
+		},
+		sandboxLeaveFuncName: func() string {
+			s.frames = s.frames[:len(s.frames)-1]
+			s.templates = s.templates[:len(s.templates)-1]
+			return ""
+		},
+		sandboxTextFuncName: func(size int) (string, error) {
+			return "", s.write(size)
+		},
+		sandboxOutputFuncName: func(v interface{}) (interface{}, error) {
+			return v, s.write(printedSize(v))
+		},
+		sandboxDirectFuncName: func(v ...interface{}) interface{} {
+			s.direct = true
+			if len(v) == 0 {
+				return nil
+			}
+			return v[0]
+		},
+		sandboxTplFuncName: s.checkTpl,
+	}
+
+	// Dynamic templates (e.g `tpl`) can't be checked before the execution.
+	for name := range s.forbidden {
+		funcs[name] = func(...interface{}) (interface{}, error) {
+			return nil, &SandboxPolicyError{Template: s.template(), Func: name}
+		}
+	}
+
+	return funcs
+}
+
+// template returns the name of the template being executed.
+func (s *sandbox) template() string {
+	if len(s.templates) > 0 {
+		return s.templates[len(s.templates)-1]
+	}
+
+	return s.file
+}
+
+// checkTpl checks the template executed by `tpl` doesn't use forbidden functions or `.Files` methods,
+// returning the template with its `tpl` calls instrumented.
+func (s *sandbox) checkTpl(v interface{}) (interface{}, error) {
+	tpl, ok := v.(string)
+	if !ok {
+		return v, nil
+	}
+
+	// The invalid templates fail when `tpl` parses them.
+	name := s.template()
+	trees, err := parseTemplate(name, []byte(tpl))
+	if err != nil {
+		return v, nil
+	}
+
+	err = s.checkTrees(name, trees)
+	if err != nil {
+		return nil, err
+	}
+
+	instrumented := false
+	for _, t := range trees {
+		instrumented = instrumentSandboxTpl(t.Root) || instrumented
+	}
+	if !instrumented {
+		return tpl, nil
+	}
+
+	return string(treesToSource(name, []byte(tpl), trees)), nil
+}
+
+// write accounts the bytes written to the output, the output of the named templates is only
+// accounted when it's written directly to the output, the captured one (e.g: `include`) will be
+// accounted when it's written.
+func (s *sandbox) write(size int) error {
+	for _, direct := range s.frames {
+		if !direct {
+			return nil
+		}
+	}
+
+	s.written += size
+	if s.written > s.config.MaxOutputBytes {
+		return &SandboxPolicyError{Template: s.template(), Reason: fmt.Sprintf("rendered output exceeds the max output size (%d bytes)", s.config.MaxOutputBytes)}
+	}
+
+	return nil
+}
+
+// printedSize returns the size of a value printed by a template action.
+func printedSize(v interface{}) int {
+	switch v := v.(type) {
+	case nil:
+		// Helm removes the `<no value>` output.
+		return 0
+	case string:
+		return len(v)
+	}
+
+	return len(fmt.Sprint(v))
+}
@@ -0,0 +1,218 @@
+package helm_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/go-helm-template/helm"
+)
+
+func TestTemplateSandbox(t *testing.T) {
+	tests := map[string]struct {
+		chart        func() *helm.Chart
+		sandbox      helm.SandboxConfig
+		expManifests string
+		expErr       bool
+		expPolicyErr *helm.SandboxPolicyError
+		expErrMsg    string
+	}{
+		"A chart that meets the sandbox policy should render correctly.": {
+			chart: func() *helm.Chart {
+				chartFS := newTestChartFS()
+				chartFS["values.yaml"] = &fstest.MapFile{Data: []byte("someValue: something")}
+				chartFS["templates/_helpers.tpl"] = &fstest.MapFile{Data: []byte(`{{- define "test.name" -}}{{ .Chart.Name }}-{{ include "test.suffix" . }}{{- end }}{{- define "test.suffix" -}}{{ .Release.Name }}{{- end }}`)}
+				chartFS["templates/something.yaml"] = &fstest.MapFile{Data: []byte("{{- /* A comment. */ -}}\nname: {{ include \"test.name\" . }}\nbraces: \"{ {{- .Release.Name -}} }\"\n{{- if .Values.someValue }}\nsomething: {{ .Values.someValue | quote }}\n{{- end }}")}
+				return mustLoadChart(chartFS)
+			},
+			expManifests: "---\n# Source: test-chart/templates/something.yaml\nname: test-chart-test\nbraces: \"{test}\"\nsomething: \"something\"\n",
+		},
+
+		"A chart using forbidden functions should fail.": {
+			chart: func() *helm.Chart {
+				chartFS := newTestChartFS()
+				chartFS["templates/something.yaml"] = &fstest.MapFile{Data: []byte(`something: {{ getHostByName "example.com" }}`)}
+				return mustLoadChart(chartFS)
+			},
+			expErr:       true,
+			expPolicyErr: &helm.SandboxPolicyError{Template: "test-chart/templates/something.yaml", Func: "getHostByName"},
+		},
+
+		"A chart using custom forbidden functions in named templates should fail.": {
+			chart: func() *helm.Chart {
+				chartFS := newTestChartFS()
+				chartFS["templates/_helpers.tpl"] = &fstest.MapFile{Data: []byte(`{{- define "test.name" -}}{{ .Chart.Name | upper }}{{- end }}`)}
+				return mustLoadChart(chartFS)
+			},
+			sandbox:      helm.SandboxConfig{ForbiddenFuncs: []string{"upper"}},
+			expErr:       true,
+			expPolicyErr: &helm.SandboxPolicyError{Template: "test-chart/templates/_helpers.tpl", Func: "upper"},
+		},
+
+		"A chart using forbidden functions dynamically should fail.": {
+			chart: func() *helm.Chart {
+				chartFS := newTestChartFS()
+				chartFS["values.yaml"] = &fstest.MapFile{Data: []byte(`someValue: '{{ lookup "v1" "Secret" "default" "test" }}'`)}
+				chartFS["templates/something.yaml"] = &fstest.MapFile{Data: []byte(`something: {{ tpl .Values.someValue . }}`)}
+				return mustLoadChart(chartFS)
+			},
+			expErr:       true,
+			expPolicyErr: &helm.SandboxPolicyError{Template: "test-chart/templates/something.yaml", Func: "lookup"},
+		},
+
+		"A chart using forbidden functions dynamically in named templates should fail.": {
+			chart: func() *helm.Chart {
+				chartFS := newTestChartFS()
+				chartFS["values.yaml"] = &fstest.MapFile{Data: []byte(`someValue: '{{ env "HOME" }}'`)}
+				chartFS["templates/_helpers.tpl"] = &fstest.MapFile{Data: []byte(`{{- define "test.value" -}}{{ tpl .Values.someValue . }}{{- end }}`)}
+				chartFS["templates/something.yaml"] = &fstest.MapFile{Data: []byte(`something: {{ include "test.value" . }}`)}
+				return mustLoadChart(chartFS)
+			},
+			expErr:       true,
+			expPolicyErr: &helm.SandboxPolicyError{Template: "test.value", Func: "env"},
+		},
+
+		"A chart using the forbidden files glob should fail.": {
+			chart: func() *helm.Chart {
+				chartFS := newTestChartFS()
+				chartFS["files/a.txt"] = &fstest.MapFile{Data: []byte("a")}
+				chartFS["templates/something.yaml"] = &fstest.MapFile{Data: []byte(`{{ $files := .Files }}something: {{ $files.Glob "files/*" | len }}`)}
+				return mustLoadChart(chartFS)
+			},
+			expErr:       true,
+			expPolicyErr: &helm.SandboxPolicyError{Template: "test-chart/templates/something.yaml", Func: "Files.Glob"},
+		},
+
+		"A chart using the forbidden files glob dynamically should fail.": {
+			chart: func() *helm.Chart {
+				chartFS := newTestChartFS()
+				chartFS["files/a.txt"] = &fstest.MapFile{Data: []byte("a")}
+				chartFS["values.yaml"] = &fstest.MapFile{Data: []byte(`someValue: '{{ tpl "{{ .Files.Glob \"files/*\" | len }}" . }}'`)}
+				chartFS["templates/something.yaml"] = &fstest.MapFile{Data: []byte(`something: {{ tpl .Values.someValue . }}`)}
+				return mustLoadChart(chartFS)
+			},
+			expErr:       true,
+			expPolicyErr: &helm.SandboxPolicyError{Template: "test-chart/templates/something.yaml", Func: "Files.Glob"},
+		},
+
+		"A chart using custom forbidden files methods should fail.": {
+			chart: func() *helm.Chart {
+				chartFS := newTestChartFS()
+				chartFS["files/a.txt"] = &fstest.MapFile{Data: []byte("a")}
+				chartFS["templates/something.yaml"] = &fstest.MapFile{Data: []byte(`something: {{ .Files.Get "files/a.txt" }}`)}
+				return mustLoadChart(chartFS)
+			},
+			sandbox:      helm.SandboxConfig{ForbiddenFilesMethods: []string{"Get"}},
+			expErr:       true,
+			expPolicyErr: &helm.SandboxPolicyError{Template: "test-chart/templates/something.yaml", Func: "Files.Get"},
+		},
+
+		"A chart using allowed files methods and tpl should render correctly.": {
+			chart: func() *helm.Chart {
+				chartFS := newTestChartFS()
+				chartFS["files/a.txt"] = &fstest.MapFile{Data: []byte("a")}
+				chartFS["values.yaml"] = &fstest.MapFile{Data: []byte(`someValue: '{{ tpl "{{ .Files.Get \"files/a.txt\" }}" . }}'`)}
+				chartFS["templates/something.yaml"] = &fstest.MapFile{Data: []byte(`something: {{ tpl .Values.someValue . }}`)}
+				return mustLoadChart(chartFS)
+			},
+			expManifests: "---\n# Source: test-chart/templates/something.yaml\nsomething: a\n",
+		},
+
+		"A chart exceeding the max named templates depth should fail.": {
+			chart: func() *helm.Chart {
+				chartFS := newTestChartFS()
+				chartFS["templates/_helpers.tpl"] = &fstest.MapFile{Data: []byte(`{{- define "test.loop" -}}{{ include "test.loop" . }}{{- end }}`)}
+				chartFS["templates/something.yaml"] = &fstest.MapFile{Data: []byte(`something: {{ include "test.loop" . }}`)}
+				return mustLoadChart(chartFS)
+			},
+			sandbox:      helm.SandboxConfig{MaxIncludeDepth: 10},
+			expErr:       true,
+			expPolicyErr: &helm.SandboxPolicyError{Template: "test.loop", Reason: "max named templates nesting depth (10) exceeded"},
+		},
+
+		"A chart exceeding the max output size should fail.": {
+			chart: func() *helm.Chart {
+				chartFS := newTestChartFS()
+				chartFS["templates/something.yaml"] = &fstest.MapFile{Data: []byte(`something: {{ repeat 100 "a" }}`)}
+				return mustLoadChart(chartFS)
+			},
+			sandbox:      helm.SandboxConfig{MaxOutputBytes: 50},
+			expErr:       true,
+			expPolicyErr: &helm.SandboxPolicyError{Template: "test-chart/templates/something.yaml", Reason: "rendered output exceeds the max output size (50 bytes)"},
+		},
+
+		"A chart generating a huge output should fail while rendering.": {
+			chart: func() *helm.Chart {
+				chartFS := newTestChartFS()
+				chartFS["templates/something.yaml"] = &fstest.MapFile{Data: []byte(`{{ range until 1000000 }}aaaaaaaaaa{{ end }}`)}
+				return mustLoadChart(chartFS)
+			},
+			sandbox:      helm.SandboxConfig{MaxOutputBytes: 50},
+			expErr:       true,
+			expPolicyErr: &helm.SandboxPolicyError{Template: "test-chart/templates/something.yaml", Reason: "rendered output exceeds the max output size (50 bytes)"},
+		},
+
+		"A chart exceeding the max output size with named templates should fail.": {
+			chart: func() *helm.Chart {
+				chartFS := newTestChartFS()
+				chartFS["templates/_helpers.tpl"] = &fstest.MapFile{Data: []byte(`{{- define "test.a" -}}{{ repeat 30 "a" }}{{- end }}`)}
+				chartFS["templates/something.yaml"] = &fstest.MapFile{Data: []byte(`a: {{ template "test.a" . }}` + "\n" + `b: {{ template "test.a" . }}`)}
+				return mustLoadChart(chartFS)
+			},
+			sandbox:      helm.SandboxConfig{MaxOutputBytes: 50},
+			expErr:       true,
+			expPolicyErr: &helm.SandboxPolicyError{Template: "test.a", Reason: "rendered output exceeds the max output size (50 bytes)"},
+		},
+
+		"A chart with included named templates should only account the output once.": {
+			chart: func() *helm.Chart {
+				chartFS := newTestChartFS()
+				chartFS["templates/_helpers.tpl"] = &fstest.MapFile{Data: []byte(`{{- define "test.a" -}}{{ repeat 30 "a" }}{{- end }}`)}
+				chartFS["templates/something.yaml"] = &fstest.MapFile{Data: []byte(`a: {{ include "test.a" . }}`)}
+				return mustLoadChart(chartFS)
+			},
+			sandbox:      helm.SandboxConfig{MaxOutputBytes: 50},
+			expManifests: "---\n# Source: test-chart/templates/something.yaml\na: aaaaaaaaaaaaaaaaaaaaaaaaaaaaaa\n",
+		},
+
+		"A chart with a template error should point to the original template line.": {
+			chart: func() *helm.Chart {
+				chartFS := newTestChartFS()
+				chartFS["templates/something.yaml"] = &fstest.MapFile{Data: []byte("{{- /* Comment. */ -}}\n\n{{- define \"test.a\" -}}\n{{ fail \"a\" }}\n{{- end }}\na: {{ .Values.a\n  | quote }}\n\nb: {{ fail \"b\" }}")}
+				return mustLoadChart(chartFS)
+			},
+			expErr:    true,
+			expErrMsg: "test-chart/templates/something.yaml:9:",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			sandbox := test.sandbox
+			gotManifests, err := helm.Template(context.TODO(), helm.TemplateConfig{
+				Chart:       test.chart(),
+				ReleaseName: "test",
+				Sandbox:     &sandbox,
+			})
+
+			if test.expErr {
+				if assert.Error(err) && test.expErrMsg != "" {
+					assert.Contains(err.Error(), test.expErrMsg)
+				}
+				if test.expPolicyErr != nil {
+					var policyErr *helm.SandboxPolicyError
+					if assert.True(errors.As(err, &policyErr)) {
+						assert.Equal(test.expPolicyErr, policyErr)
+					}
+				}
+			} else if assert.NoError(err) {
+				assert.Equal(test.expManifests, gotManifests)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package helm
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"helm.sh/helm/v4/pkg/chart/common"
+)
+
+const snippetTemplateName = "templates/go-helm-template-snippet"
+
+// RenderSnippet renders a template snippet (e.g: `{{ include "mychart.fullname" . }}`) in the context
+// of the chart (Values, Release, Chart, Capabilities...), the same way `Template` renders the chart templates.
+//
+// The chart templates are not rendered, but their named templates (e.g: `_helpers.tpl`) can be used
+// by the snippet. This can be handy to unit test named templates.
+//
+// The snippet is rendered with the `chart` argument, `config.Chart` is ignored. There is no render result
+// to return the missing values, so `MissingValuesModeWarn` is not supported.
+func RenderSnippet(ctx context.Context, chart *Chart, config TemplateConfig, snippet string) (_ string, err error) {
+	ctx, span := startSpan(ctx, config.TracerProvider, "RenderSnippet")
+	defer func() { endSpan(span, err) }()
+
+	if config.MissingValues == MissingValuesModeWarn {
+		return "", fmt.Errorf("invalid missing values mode: %q mode is not supported on snippets", config.MissingValues)
+	}
+
+	config.Chart = chart
+	c, err := config.prepare()
+	if err != nil {
+		return "", err
+	}
+	span.SetAttributes(chartTraceAttrs(c.Name(), c.Metadata.Version)...)
+
+	// The coverage needs the original templates, so instrument them before removing anything.
+	var instruments []renderInstrument
+	if config.Coverage != nil {
+		cov := coverageInstrument{coverage: config.Coverage}
+		c, err = copyChart(c, func(name string, data []byte) ([]byte, error) {
+			trees, err := parseTemplate(name, data)
+			if err != nil {
+				return nil, fmt.Errorf("could not parse template %q: %w", name, err)
+			}
+
+			err = cov.instrumentTemplate(name, data, trees)
+			if err != nil {
+				return nil, err
+			}
+
+			return treesToSource(name, data, trees), nil
+		})
+		if err != nil {
+			return "", fmt.Errorf("could not prepare chart: %w", err)
+		}
+		instruments = append(instruments, preparedInstrument{cov})
+	}
+
+	// Only keep the named templates from the chart templates, so we only render the snippet.
+	c, err = copyChart(c, func(name string, data []byte) ([]byte, error) {
+		if strings.HasPrefix(path.Base(name), "_") {
+			return data, nil
+		}
+
+		trees, err := parseTemplate(name, data)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse template %q: %w", name, err)
+		}
+		delete(trees, name)
+
+		return treesToSource(name, data, trees), nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("could not prepare chart: %w", err)
+	}
+	c.Templates = append(c.Templates, &common.File{Name: snippetTemplateName, Data: []byte(snippet)})
+
+	// Only the strict mode makes sense, there is no result to return the missing values.
+	var checker *valuesChecker
+	if config.MissingValues == MissingValuesModeError {
+		checker = newValuesChecker(config.MissingValues)
+		instruments = append(instruments, checker)
+	}
+
+	_, files, _, err := renderFiles(ctx, c, config, instruments...)
+	if err != nil {
+		if checker != nil {
+			err = checker.renderError(err)
+		}
+		return "", fmt.Errorf("could not render snippet correctly: %w", err)
+	}
+
+	result, ok := files[path.Join(c.Name(), snippetTemplateName)]
+	if !ok {
+		return "", fmt.Errorf("snippet has not been rendered, library charts are not supported")
+	}
+
+	return result, nil
+}
+
+// RenderNamedTemplate renders a chart named template (e.g: `mychart.labels`) the same way
+// `include` would do it, in the context of the chart (Values, Release, Chart, Capabilities...).
+func RenderNamedTemplate(ctx context.Context, chart *Chart, config TemplateConfig, name string) (string, error) {
+	return RenderSnippet(ctx, chart, config, fmt.Sprintf("{{ include %q . }}", name))
+}
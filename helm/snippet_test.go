@@ -0,0 +1,159 @@
+package helm_test
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/go-helm-template/helm"
+)
+
+func newTestSnippetChart() *helm.Chart {
+	chartFS := newTestChartFS()
+	chartFS["values.yaml"] = &fstest.MapFile{Data: []byte("labels:\n  team: test")}
+	chartFS["templates/_helpers.tpl"] = &fstest.MapFile{Data: []byte(`
+{{- define "test.fullname" -}}
+{{ .Chart.Name }}-{{ .Release.Name }}
+{{- end }}
+
+{{- define "test.labels" -}}
+app: {{ include "test.fullname" . }}
+{{- with .Values.labels }}
+{{ toYaml . }}
+{{- end }}
+{{- end }}
+`)}
+	chartFS["templates/something.yaml"] = &fstest.MapFile{Data: []byte(`
+{{- define "test.other" -}}
+other-{{ .Release.Namespace }}
+{{- end -}}
+something: {{ required "a value is required" .Values.missing }}
+`)}
+
+	return mustLoadChart(chartFS)
+}
+
+func TestRenderSnippet(t *testing.T) {
+	tests := map[string]struct {
+		config    helm.TemplateConfig
+		snippet   string
+		expResult string
+		expErr    bool
+	}{
+		"A snippet without template actions should be returned as it is.": {
+			config:    helm.TemplateConfig{ReleaseName: "test"},
+			snippet:   "something",
+			expResult: "something",
+		},
+
+		"A snippet should be rendered with the chart context.": {
+			config:    helm.TemplateConfig{ReleaseName: "test", Namespace: "test-ns"},
+			snippet:   `{{ .Chart.Name }} {{ .Release.Name }} {{ .Release.Namespace }} {{ .Values.labels.team }} {{ .Capabilities.KubeVersion.Major }}`,
+			expResult: "test-chart test test-ns test 1",
+		},
+
+		"A snippet should be able to include chart named templates.": {
+			config:    helm.TemplateConfig{ReleaseName: "test"},
+			snippet:   `{{ include "test.fullname" . }}`,
+			expResult: "test-chart-test",
+		},
+
+		"A snippet should be able to include named templates defined on regular templates.": {
+			config:    helm.TemplateConfig{ReleaseName: "test", Namespace: "test-ns"},
+			snippet:   `{{ include "test.other" . }}`,
+			expResult: "other-test-ns",
+		},
+
+		"A snippet should use the custom values.": {
+			config: helm.TemplateConfig{
+				ReleaseName: "test",
+				Values: map[string]interface{}{
+					"labels": map[string]interface{}{"team": "custom"},
+				},
+			},
+			snippet:   `{{ include "test.labels" . }}`,
+			expResult: "app: test-chart-test\nteam: custom",
+		},
+
+		"A snippet including a missing named template should fail.": {
+			config:  helm.TemplateConfig{ReleaseName: "test"},
+			snippet: `{{ include "test.missing" . }}`,
+			expErr:  true,
+		},
+
+		"A snippet with the missing values error mode should fail on missing values.": {
+			config:  helm.TemplateConfig{ReleaseName: "test", MissingValues: helm.MissingValuesModeError},
+			snippet: `{{ .Values.missing }}`,
+			expErr:  true,
+		},
+
+		"A snippet with the missing values warn mode should fail.": {
+			config:  helm.TemplateConfig{ReleaseName: "test", MissingValues: helm.MissingValuesModeWarn},
+			snippet: `{{ .Values.labels.team }}`,
+			expErr:  true,
+		},
+
+		"A snippet should use the chart argument instead of the config chart.": {
+			config:    helm.TemplateConfig{ReleaseName: "test", Chart: mustLoadChart(newTestChartFS())},
+			snippet:   `{{ .Values.labels.team }}`,
+			expResult: "test",
+		},
+
+		"An invalid snippet should fail.": {
+			config:  helm.TemplateConfig{ReleaseName: "test"},
+			snippet: `{{ include "test.fullname" . `,
+			expErr:  true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			gotResult, err := helm.RenderSnippet(context.TODO(), newTestSnippetChart(), test.config, test.snippet)
+
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expResult, gotResult)
+			}
+		})
+	}
+}
+
+func TestRenderNamedTemplate(t *testing.T) {
+	tests := map[string]struct {
+		config    helm.TemplateConfig
+		name      string
+		expResult string
+		expErr    bool
+	}{
+		"A named template should be rendered.": {
+			config:    helm.TemplateConfig{ReleaseName: "test"},
+			name:      "test.labels",
+			expResult: "app: test-chart-test\nteam: test",
+		},
+
+		"A missing named template should fail.": {
+			config: helm.TemplateConfig{ReleaseName: "test"},
+			name:   "test.missing",
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			gotResult, err := helm.RenderNamedTemplate(context.TODO(), newTestSnippetChart(), test.config, test.name)
+
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expResult, gotResult)
+			}
+		})
+	}
+}
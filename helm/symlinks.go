@@ -0,0 +1,80 @@
+package helm
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// maxSymlinksHops is the maximum number of symlinks followed to resolve a path, like the
+// Linux kernel `MAXSYMLINKS`.
+const maxSymlinksHops = 40
+
+// SymlinksMode is how the chart symlinks are handled when loading a chart.
+type SymlinksMode string
+
+const (
+	// SymlinksModeWarn skips the chart symlinks logging a warning.
+	SymlinksModeWarn SymlinksMode = ""
+	// SymlinksModeError fails the chart loading when the chart has symlinks.
+	SymlinksModeError SymlinksMode = "error"
+	// SymlinksModeFollow loads the symlinks targets (files and directories) as if they were on the
+	// symlinks paths, the symlinks targets must be inside the symlinks root and can't have loops.
+	SymlinksModeFollow SymlinksMode = "follow"
+)
+
+func (m SymlinksMode) validate() error {
+	switch m {
+	case SymlinksModeError, SymlinksModeWarn, SymlinksModeFollow:
+		return nil
+	}
+
+	return fmt.Errorf("unknown symlinks mode %q", m)
+}
+
+// resolveSymlink returns the path of the symlink final target on the root fs.FS, it fails if any
+// of the symlinks of the path points outside of the root, has an absolute target or there is a loop.
+func resolveSymlink(root fs.FS, name string) (string, error) {
+	resolved := "."
+	pending := strings.Split(name, "/")
+	hops := 0
+	for len(pending) > 0 {
+		// The resolved path has no symlinks, so `..` can be resolved lexically.
+		p := path.Join(resolved, pending[0])
+		pending = pending[1:]
+		if p == ".." || strings.HasPrefix(p, "../") {
+			return "", fmt.Errorf("symlink %s target is outside of the symlinks root", name)
+		}
+
+		info, err := fs.Lstat(root, p)
+		if err != nil {
+			return "", fmt.Errorf("could not resolve symlink %s: %w", name, err)
+		}
+		if info.Mode().Type() != fs.ModeSymlink {
+			resolved = p
+			continue
+		}
+
+		hops++
+		if hops > maxSymlinksHops {
+			return "", fmt.Errorf("could not resolve symlink %s: too many symlinks (symlinks loop)", name)
+		}
+
+		target, err := fs.ReadLink(root, p)
+		if err != nil {
+			return "", fmt.Errorf("could not read symlink %s: %w", p, err)
+		}
+		if path.IsAbs(target) {
+			return "", fmt.Errorf("symlink %s has an absolute target, only relative targets are supported", p)
+		}
+		pending = append(strings.Split(target, "/"), pending...)
+	}
+
+	return resolved, nil
+}
+
+// isSubPath returns true if the path is the directory or it's inside of it.
+func isSubPath(p, dir string) bool {
+	return dir == "." || p == dir || strings.HasPrefix(p, dir+"/")
+}
@@ -0,0 +1,221 @@
+package helm_test
+
+import (
+	"bytes"
+	"context"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/go-helm-template/helm"
+)
+
+func TestLoadChartSymlinks(t *testing.T) {
+	const helpers = `{{- define "name" }}shared{{ end }}`
+
+	newChartFS := func(files map[string]*fstest.MapFile) fstest.MapFS {
+		chartFS := newTestChartFS()
+		chartFS["templates/cm.yaml"] = &fstest.MapFile{Data: []byte(`name: {{ include "name" . }}`)}
+		for k, v := range files {
+			chartFS[k] = v
+		}
+		return chartFS
+	}
+	symlink := func(target string) *fstest.MapFile {
+		return &fstest.MapFile{Mode: fs.ModeSymlink, Data: []byte(target)}
+	}
+
+	monorepoFS := fstest.MapFS{
+		"shared/_helpers.tpl":             &fstest.MapFile{Data: []byte(helpers)},
+		"charts/a/Chart.yaml":             &fstest.MapFile{Data: []byte("apiVersion: v2\nname: test-chart\nversion: 0.1.0")},
+		"charts/a/templates/cm.yaml":      &fstest.MapFile{Data: []byte(`name: {{ include "name" . }}`)},
+		"charts/a/templates/_helpers.tpl": symlink("../../../shared/_helpers.tpl"),
+	}
+
+	monorepoDir := t.TempDir()
+	for name, f := range monorepoFS {
+		p := filepath.Join(monorepoDir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+		if f.Mode == fs.ModeSymlink {
+			require.NoError(t, os.Symlink(string(f.Data), p))
+			continue
+		}
+		require.NoError(t, os.WriteFile(p, f.Data, 0o644))
+	}
+
+	tests := map[string]struct {
+		fs          func(t *testing.T) fs.FS
+		config      helm.LoadChartConfig
+		expOut      string
+		expWarnings string
+		expErr      bool
+	}{
+		"Charts with symlinks should skip them with a warning by default.": {
+			fs: func(t *testing.T) fs.FS {
+				return newChartFS(map[string]*fstest.MapFile{
+					"templates/_helpers.tpl": {Data: []byte(helpers)},
+					"templates/link.yaml":    symlink("cm.yaml"),
+				})
+			},
+			expOut:      "---\n# Source: test-chart/templates/cm.yaml\nname: shared\n",
+			expWarnings: "level=WARN msg=\"chart symlink skipped\" path=templates/link.yaml\n",
+		},
+
+		"Charts with symlinks in error mode should fail.": {
+			fs: func(t *testing.T) fs.FS {
+				return newChartFS(map[string]*fstest.MapFile{
+					"shared.tpl":             {Data: []byte(helpers)},
+					"templates/_helpers.tpl": symlink("../shared.tpl"),
+				})
+			},
+			config: helm.LoadChartConfig{Symlinks: helm.SymlinksModeError},
+			expErr: true,
+		},
+
+		"Ignored symlinks in error mode should not fail.": {
+			fs: func(t *testing.T) fs.FS {
+				return newChartFS(map[string]*fstest.MapFile{
+					"templates/_helpers.tpl": {Data: []byte(helpers)},
+					"templates/link.yaml":    symlink("cm.yaml"),
+				})
+			},
+			config: helm.LoadChartConfig{Symlinks: helm.SymlinksModeError, IgnorePatterns: []string{"link.yaml"}},
+			expOut: "---\n# Source: test-chart/templates/cm.yaml\nname: shared\n",
+		},
+
+		"Charts with file symlinks in follow mode should load the targets.": {
+			fs: func(t *testing.T) fs.FS {
+				return newChartFS(map[string]*fstest.MapFile{
+					"shared.tpl":             {Data: []byte(helpers)},
+					"templates/_helpers.tpl": symlink("../link.tpl"),
+					"link.tpl":               symlink("shared.tpl"),
+				})
+			},
+			config: helm.LoadChartConfig{Symlinks: helm.SymlinksModeFollow},
+			expOut: "---\n# Source: test-chart/templates/cm.yaml\nname: shared\n",
+		},
+
+		"Charts with directory symlinks in follow mode should load the target directories files.": {
+			fs: func(t *testing.T) fs.FS {
+				return newChartFS(map[string]*fstest.MapFile{
+					"shared/_helpers.tpl": {Data: []byte(helpers)},
+					"templates/shared":    symlink("../shared"),
+				})
+			},
+			config: helm.LoadChartConfig{Symlinks: helm.SymlinksModeFollow, IgnorePatterns: []string{"/shared/"}},
+			expOut: "---\n# Source: test-chart/templates/cm.yaml\nname: shared\n",
+		},
+
+		"Charts with symlinks in follow mode should load the targets outside of the chart inside the symlinks root.": {
+			fs: func(t *testing.T) fs.FS {
+				f, err := fs.Sub(monorepoFS, "charts/a")
+				require.NoError(t, err)
+				return f
+			},
+			config: helm.LoadChartConfig{Symlinks: helm.SymlinksModeFollow, SymlinksRoot: monorepoFS, SymlinksRootChartDir: "charts/a"},
+			expOut: "---\n# Source: test-chart/templates/cm.yaml\nname: shared\n",
+		},
+
+		"Charts with symlinks in follow mode should load the targets outside of the chart inside the symlinks root on disk.": {
+			fs: func(t *testing.T) fs.FS {
+				return os.DirFS(filepath.Join(monorepoDir, "charts", "a"))
+			},
+			config: helm.LoadChartConfig{Symlinks: helm.SymlinksModeFollow, SymlinksRoot: os.DirFS(monorepoDir), SymlinksRootChartDir: "charts/a"},
+			expOut: "---\n# Source: test-chart/templates/cm.yaml\nname: shared\n",
+		},
+
+		"Charts with symlinks in follow mode pointing outside of the symlinks root should fail.": {
+			fs: func(t *testing.T) fs.FS {
+				f, err := fs.Sub(monorepoFS, "charts/a")
+				require.NoError(t, err)
+				return f
+			},
+			config: helm.LoadChartConfig{Symlinks: helm.SymlinksModeFollow},
+			expErr: true,
+		},
+
+		"Charts with symlinks on disk in follow mode pointing outside of the symlinks root should fail.": {
+			fs: func(t *testing.T) fs.FS {
+				return os.DirFS(filepath.Join(monorepoDir, "charts", "a"))
+			},
+			config: helm.LoadChartConfig{Symlinks: helm.SymlinksModeFollow},
+			expErr: true,
+		},
+
+		"Charts with symlinks in follow mode with absolute targets should fail.": {
+			fs: func(t *testing.T) fs.FS {
+				return newChartFS(map[string]*fstest.MapFile{
+					"templates/_helpers.tpl": symlink("/etc/passwd"),
+				})
+			},
+			config: helm.LoadChartConfig{Symlinks: helm.SymlinksModeFollow},
+			expErr: true,
+		},
+
+		"Charts with symlinks loops in follow mode should fail.": {
+			fs: func(t *testing.T) fs.FS {
+				return newChartFS(map[string]*fstest.MapFile{
+					"templates/a.tpl": symlink("b.tpl"),
+					"templates/b.tpl": symlink("a.tpl"),
+				})
+			},
+			config: helm.LoadChartConfig{Symlinks: helm.SymlinksModeFollow},
+			expErr: true,
+		},
+
+		"Charts with directory symlinks to a parent directory in follow mode should fail.": {
+			fs: func(t *testing.T) fs.FS {
+				return newChartFS(map[string]*fstest.MapFile{
+					"templates/_helpers.tpl": {Data: []byte(helpers)},
+					"templates/parent/root":  symlink("../.."),
+				})
+			},
+			config: helm.LoadChartConfig{Symlinks: helm.SymlinksModeFollow},
+			expErr: true,
+		},
+
+		"Invalid symlinks mode should fail.": {
+			fs: func(t *testing.T) fs.FS {
+				return newChartFS(nil)
+			},
+			config: helm.LoadChartConfig{Symlinks: "unknown"},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			var logs bytes.Buffer
+			test.config.Logger = slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{
+				Level: slog.LevelWarn,
+				ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+					if a.Key == slog.TimeKey {
+						return slog.Attr{}
+					}
+					return a
+				},
+			}))
+
+			chart, err := helm.LoadChartWithConfig(context.TODO(), test.fs(t), test.config)
+			if test.expErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+
+			out, err := helm.Template(context.TODO(), helm.TemplateConfig{ReleaseName: "test", Chart: chart})
+			require.NoError(err)
+			assert.Equal(test.expOut, out)
+			assert.Equal(test.expWarnings, logs.String())
+		})
+	}
+}
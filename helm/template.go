@@ -0,0 +1,422 @@
+package helm
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"text/template/parse"
+
+	"helm.sh/helm/v4/pkg/chart/common"
+	chartv2 "helm.sh/helm/v4/pkg/chart/v2"
+)
+
+// parseTemplate parses a template source without checking the template functions exist,
+// returning the template trees by name (the template file itself and all the defined templates).
+func parseTemplate(name string, data []byte) (map[string]*parse.Tree, error) {
+	trees := map[string]*parse.Tree{}
+	t := parse.New(name)
+	t.Mode = parse.SkipFuncCheck
+	_, err := t.Parse(string(data), "", "", trees)
+	if err != nil {
+		return nil, err
+	}
+
+	return trees, nil
+}
+
+// treesToSource converts parsed template trees (of a single template file) back to the
+// template source, the tree of the template file must be the one named as `name` and `source`
+// the template source the trees have been parsed from.
+//
+// The nodes are written on the same line they had on the original source (padding with empty
+// comments when required), so the template errors point to the lines of the original source.
+func treesToSource(name string, source []byte, trees map[string]*parse.Tree) []byte {
+	for _, t := range trees {
+		escapeTextNodes(t.Root)
+	}
+
+	// Defined templates are written in place, sorted to be deterministic.
+	defines := make([]string, 0, len(trees))
+	for n, t := range trees {
+		if n != name && t.Root != nil {
+			defines = append(defines, n)
+		}
+	}
+	sort.Slice(defines, func(i, j int) bool { return trees[defines[i]].Root.Position() < trees[defines[j]].Root.Position() })
+
+	w := newSourceWriter(source)
+	writeDefines := func(before parse.Pos) {
+		for len(defines) > 0 && trees[defines[0]].Root.Position() < before {
+			fmt.Fprintf(w, "{{define %q}}", defines[0])
+			w.writeList(trees[defines[0]].Root)
+			w.WriteString("{{end}}")
+			defines = defines[1:]
+		}
+	}
+
+	if t, ok := trees[name]; ok && t.Root != nil {
+		for _, n := range t.Root.Nodes {
+			// The instrumentation nodes don't have a position on the original source.
+			if !isInstrumentationNode(n) {
+				writeDefines(n.Position())
+			}
+			w.writeNode(n)
+		}
+	}
+	writeDefines(parse.Pos(len(source) + 1))
+
+	return []byte(w.String())
+}
+
+// sourceWriter writes template nodes keeping them on the line they had on the original source.
+type sourceWriter struct {
+	strings.Builder
+	// lineOffsets are the source offsets where each line starts.
+	lineOffsets []int
+	line        int
+}
+
+func newSourceWriter(source []byte) *sourceWriter {
+	lineOffsets := []int{0}
+	for i, c := range source {
+		if c == '\n' {
+			lineOffsets = append(lineOffsets, i+1)
+		}
+	}
+
+	return &sourceWriter{lineOffsets: lineOffsets, line: 1}
+}
+
+func (w *sourceWriter) WriteString(s string) (int, error) {
+	w.line += strings.Count(s, "\n")
+	return w.Builder.WriteString(s)
+}
+
+func (w *sourceWriter) Write(p []byte) (int, error) {
+	return w.WriteString(string(p))
+}
+
+// padTo adds an empty comment with new lines until the writer reaches the line of the source position.
+func (w *sourceWriter) padTo(pos parse.Pos) {
+	line := sort.SearchInts(w.lineOffsets, int(pos)+1)
+	if line > w.line {
+		w.WriteString("{{/*" + strings.Repeat("\n", line-w.line) + "*/}}")
+	}
+}
+
+func (w *sourceWriter) writeList(l *parse.ListNode) {
+	if l == nil {
+		return
+	}
+
+	for _, n := range l.Nodes {
+		w.writeNode(n)
+	}
+}
+
+func (w *sourceWriter) writeNode(n parse.Node) {
+	if !isInstrumentationNode(n) {
+		w.padTo(n.Position())
+	}
+
+	switch n := n.(type) {
+	case *parse.IfNode:
+		w.writeBranch("if", &n.BranchNode)
+	case *parse.RangeNode:
+		w.writeBranch("range", &n.BranchNode)
+	case *parse.WithNode:
+		w.writeBranch("with", &n.BranchNode)
+	default:
+		w.WriteString(n.String())
+	}
+}
+
+func (w *sourceWriter) writeBranch(keyword string, n *parse.BranchNode) {
+	fmt.Fprintf(w, "{{%s %s}}", keyword, n.Pipe)
+	w.writeList(n.List)
+	if n.ElseList != nil {
+		w.padTo(n.ElseList.Position())
+		w.WriteString("{{else}}")
+		w.writeList(n.ElseList)
+	}
+	w.WriteString("{{end}}")
+}
+
+// instrumentationFuncPrefix is the prefix of the template functions used to instrument the templates.
+const instrumentationFuncPrefix = "goHelmTemplate"
+
+// isInstrumentationNode returns true if the node is an instrumentation function call added to the template.
+func isInstrumentationNode(n parse.Node) bool {
+	action, ok := n.(*parse.ActionNode)
+	if !ok || len(action.Pipe.Cmds) == 0 || len(action.Pipe.Cmds[0].Args) == 0 {
+		return false
+	}
+
+	id, ok := action.Pipe.Cmds[0].Args[0].(*parse.IdentifierNode)
+	return ok && strings.HasPrefix(id.Ident, instrumentationFuncPrefix)
+}
+
+// sourcePosition returns the line and column (1 based) of a template source offset.
+func sourcePosition(source []byte, offset int) (line, col int) {
+	before := source[:offset]
+	line = bytes.Count(before, []byte("\n")) + 1
+	col = offset - bytes.LastIndexByte(before, '\n')
+
+	return line, col
+}
+
+// sourceSpan is a span of a template source, from the start offset (included) to the
+// end offset (excluded).
+type sourceSpan struct {
+	start int
+	end   int
+}
+
+// templateActions returns the spans of the template source actions (from `{{` to `}}`). The string
+// and character literals are skipped, so the delimiters inside them are not taken into account.
+func templateActions(source []byte) []sourceSpan {
+	spans := []sourceSpan{}
+	for i := 0; i < len(source); {
+		start := bytes.Index(source[i:], []byte("{{"))
+		if start < 0 {
+			break
+		}
+		start += i
+
+		end := templateActionEnd(source, start+2)
+		spans = append(spans, sourceSpan{start: start, end: end})
+		i = end
+	}
+
+	return spans
+}
+
+// templateActionEnd returns the end offset of an action that starts on `i` (after the left delimiter).
+func templateActionEnd(source []byte, i int) int {
+	if i+1 < len(source) && source[i] == '-' && isTemplateSpace(source[i+1]) {
+		i += 2
+	}
+
+	// Comments.
+	if bytes.HasPrefix(source[i:], []byte("/*")) {
+		end := bytes.Index(source[i+2:], []byte("*/"))
+		if end < 0 {
+			return len(source)
+		}
+		i += 2 + end + 2
+	}
+
+	for i < len(source) {
+		switch c := source[i]; c {
+		case '"', '\'':
+			for i++; i < len(source) && source[i] != c && source[i] != '\n'; i++ {
+				if source[i] == '\\' {
+					i++
+				}
+			}
+			i++
+		case '`':
+			end := bytes.IndexByte(source[i+1:], '`')
+			if end < 0 {
+				return len(source)
+			}
+			i += end + 2
+		case '}':
+			if i+1 < len(source) && source[i+1] == '}' {
+				return i + 2
+			}
+			i++
+		default:
+			i++
+		}
+	}
+
+	return len(source)
+}
+
+func isTemplateSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
+
+// spanAt returns the span that contains the source offset, the spans must be sorted.
+func spanAt(spans []sourceSpan, offset int) (sourceSpan, bool) {
+	i := sort.Search(len(spans), func(i int) bool { return spans[i].end > offset })
+	if i == len(spans) || spans[i].start > offset {
+		return sourceSpan{}, false
+	}
+
+	return spans[i], true
+}
+
+// escapeTextNodes splits the text nodes that end with `{`, so when the tree is converted
+// back to source, the text doesn't get merged with the next action delimiter (e.g: `{{{`).
+func escapeTextNodes(root *parse.ListNode) {
+	walkNode(root, func(n parse.Node) bool {
+		l, ok := n.(*parse.ListNode)
+		if !ok || l == nil {
+			return true
+		}
+
+		nodes := make([]parse.Node, 0, len(l.Nodes))
+		for _, c := range l.Nodes {
+			t, ok := c.(*parse.TextNode)
+			if !ok || !bytes.HasSuffix(t.Text, []byte("{")) {
+				nodes = append(nodes, c)
+				continue
+			}
+
+			t.Text = t.Text[:len(t.Text)-1]
+			nodes = append(nodes, t, openBraceActionNode())
+		}
+		l.Nodes = nodes
+
+		return true
+	})
+}
+
+// openBraceActionNode returns an action that prints `{`.
+func openBraceActionNode() parse.Node {
+	trees, err := parseTemplate("brace", []byte(`{{ "{" }}`))
+	if err != nil {
+		// Should never happen, it's a static template.
+		panic(err)
+	}
+	return trees["brace"].Root.Nodes[0]
+}
+
+// walkNode walks the template node tree depth first calling fn on every node, if fn returns
+// false the children of the node will not be walked.
+func walkNode(node parse.Node, fn func(parse.Node) bool) {
+	if node == nil {
+		return
+	}
+
+	if !fn(node) {
+		return
+	}
+
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Nodes {
+			walkNode(c, fn)
+		}
+	case *parse.ActionNode:
+		walkNode(n.Pipe, fn)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, d := range n.Decl {
+			walkNode(d, fn)
+		}
+		for _, c := range n.Cmds {
+			walkNode(c, fn)
+		}
+	case *parse.CommandNode:
+		for _, a := range n.Args {
+			walkNode(a, fn)
+		}
+	case *parse.ChainNode:
+		walkNode(n.Node, fn)
+	case *parse.IfNode:
+		walkBranchNode(&n.BranchNode, fn)
+	case *parse.RangeNode:
+		walkBranchNode(&n.BranchNode, fn)
+	case *parse.WithNode:
+		walkBranchNode(&n.BranchNode, fn)
+	case *parse.TemplateNode:
+		walkNode(n.Pipe, fn)
+	}
+}
+
+func walkBranchNode(n *parse.BranchNode, fn func(parse.Node) bool) {
+	walkNode(n.Pipe, fn)
+	if n.List != nil {
+		walkNode(n.List, fn)
+	}
+	if n.ElseList != nil {
+		walkNode(n.ElseList, fn)
+	}
+}
+
+// chartTemplate is a template of a chart or any of its subcharts.
+type chartTemplate struct {
+	// Name is the full name of the template as Helm knows it (e.g: `mychart/charts/sub/templates/x.yaml`).
+	Name string
+	// File is the chart template file.
+	File *common.File
+}
+
+// chartTemplates returns all the templates of a chart including the subcharts ones.
+func chartTemplates(chart *chartv2.Chart) []chartTemplate {
+	tpls := []chartTemplate{}
+	for _, t := range chart.Templates {
+		if t == nil {
+			continue
+		}
+		tpls = append(tpls, chartTemplate{Name: path.Join(chart.ChartFullPath(), t.Name), File: t})
+	}
+
+	for _, dep := range chart.Dependencies() {
+		tpls = append(tpls, chartTemplates(dep)...)
+	}
+
+	return tpls
+}
+
+// copyChart returns a copy of the chart (and its subcharts) so the chart can be modified without
+// affecting the original one, the templates can be mutated with `mutateTemplate`, the files data
+// are shared so these should not be modified in place.
+func copyChart(chart *chartv2.Chart, mutateTemplate func(fullName string, data []byte) ([]byte, error)) (*chartv2.Chart, error) {
+	c := *chart
+
+	// The dependencies processing mutates the metadata.
+	if chart.Metadata != nil {
+		md := *chart.Metadata
+		if md.Dependencies != nil {
+			md.Dependencies = make([]*chartv2.Dependency, 0, len(chart.Metadata.Dependencies))
+			for _, d := range chart.Metadata.Dependencies {
+				if d != nil {
+					dc := *d
+					d = &dc
+				}
+				md.Dependencies = append(md.Dependencies, d)
+			}
+		}
+		c.Metadata = &md
+	}
+
+	c.Templates = make([]*common.File, 0, len(chart.Templates))
+	for _, t := range chart.Templates {
+		if t == nil {
+			continue
+		}
+
+		tc := *t
+		if mutateTemplate != nil {
+			data, err := mutateTemplate(path.Join(chart.ChartFullPath(), t.Name), t.Data)
+			if err != nil {
+				return nil, err
+			}
+			tc.Data = data
+		}
+		c.Templates = append(c.Templates, &tc)
+	}
+
+	deps := make([]*chartv2.Chart, 0, len(chart.Dependencies()))
+	for _, dep := range chart.Dependencies() {
+		dc, err := copyChart(dep, mutateTemplate)
+		if err != nil {
+			return nil, err
+		}
+		deps = append(deps, dc)
+	}
+	c.SetDependencies(deps...)
+
+	return &c, nil
+}
@@ -0,0 +1,54 @@
+package helm
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+const tracerName = "github.com/slok/go-helm-template/helm"
+
+// Tracing span attributes.
+const (
+	traceAttrChartName    = attribute.Key("helm.chart.name")
+	traceAttrChartVersion = attribute.Key("helm.chart.version")
+	traceAttrFiles        = attribute.Key("helm.chart.files")
+	traceAttrDependencies = attribute.Key("helm.chart.dependencies")
+	traceAttrTemplates    = attribute.Key("helm.templates")
+	traceAttrManifests    = attribute.Key("helm.manifests")
+	traceAttrHooks        = attribute.Key("helm.hooks")
+	traceAttrCached       = attribute.Key("helm.chart.cached")
+)
+
+// noTracer is used when there is no tracer provider, to not create spans on the user traces.
+var noTracer = noop.NewTracerProvider().Tracer(tracerName)
+
+// startSpan starts a tracing span using the tracer provider, if the tracer provider is missing
+// the span is not recorded.
+func startSpan(ctx context.Context, tp trace.TracerProvider, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	tracer := noTracer
+	if tp != nil {
+		tracer = tp.Tracer(tracerName)
+	}
+
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan ends the span recording the error if any.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func chartTraceAttrs(name, version string) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		traceAttrChartName.String(name),
+		traceAttrChartVersion.String(version),
+	}
+}
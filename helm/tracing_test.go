@@ -0,0 +1,142 @@
+package helm_test
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/slok/go-helm-template/helm"
+)
+
+type testSpan struct {
+	Name   string
+	Parent string
+	Attrs  map[attribute.Key]attribute.Value
+	Status codes.Code
+}
+
+func TestTracing(t *testing.T) {
+	chartFS := newTestChartFS()
+	chartFS["values.yaml"] = &fstest.MapFile{Data: []byte("name: test\n")}
+	chartFS["templates/cm.yaml"] = &fstest.MapFile{Data: []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: {{ .Values.name }}\n---\napiVersion: v1\nkind: Secret\nmetadata:\n  name: {{ .Values.name }}\n")}
+	chartFS["templates/hook.yaml"] = &fstest.MapFile{Data: []byte("apiVersion: v1\nkind: Pod\nmetadata:\n  name: hook\n  annotations:\n    helm.sh/hook: test\n")}
+	chartFS["templates/fail.yaml"] = &fstest.MapFile{Data: []byte(`{{ if .Values.fail }}{{ fail "failed" }}{{ end }}`)}
+
+	tests := map[string]struct {
+		config   helm.TemplateConfig
+		expErr   bool
+		expSpans []testSpan
+	}{
+		"Rendering a chart should trace the rendering stages.": {
+			config: helm.TemplateConfig{
+				ShowFiles:   []string{"templates/cm.yaml"},
+				EnableHooks: true,
+			},
+			expSpans: []testSpan{
+				{Name: "CoalesceValues", Parent: "Render", Attrs: map[attribute.Key]attribute.Value{
+					"helm.chart.name":         attribute.StringValue("test-chart"),
+					"helm.chart.version":      attribute.StringValue("0.1.0"),
+					"helm.chart.dependencies": attribute.IntValue(0),
+				}},
+				{Name: "ExecuteTemplates", Parent: "Render", Attrs: map[attribute.Key]attribute.Value{
+					"helm.chart.name":    attribute.StringValue("test-chart"),
+					"helm.chart.version": attribute.StringValue("0.1.0"),
+					"helm.templates":     attribute.IntValue(3),
+				}},
+				{Name: "FilterFiles", Parent: "Render", Attrs: map[attribute.Key]attribute.Value{
+					"helm.manifests": attribute.IntValue(2),
+				}},
+				{Name: "AssembleHooks", Parent: "Render", Attrs: map[attribute.Key]attribute.Value{
+					"helm.hooks": attribute.IntValue(1),
+				}},
+				{Name: "Render", Attrs: map[attribute.Key]attribute.Value{
+					"helm.chart.name":    attribute.StringValue("test-chart"),
+					"helm.chart.version": attribute.StringValue("0.1.0"),
+					"helm.manifests":     attribute.IntValue(2),
+					"helm.hooks":         attribute.IntValue(1),
+				}},
+			},
+		},
+
+		"Failing to render a chart should trace the error.": {
+			config: helm.TemplateConfig{
+				Values: map[string]interface{}{"fail": true},
+			},
+			expErr: true,
+			expSpans: []testSpan{
+				{Name: "CoalesceValues", Parent: "Render", Attrs: map[attribute.Key]attribute.Value{
+					"helm.chart.name":         attribute.StringValue("test-chart"),
+					"helm.chart.version":      attribute.StringValue("0.1.0"),
+					"helm.chart.dependencies": attribute.IntValue(0),
+				}},
+				{Name: "ExecuteTemplates", Parent: "Render", Status: codes.Error, Attrs: map[attribute.Key]attribute.Value{
+					"helm.chart.name":    attribute.StringValue("test-chart"),
+					"helm.chart.version": attribute.StringValue("0.1.0"),
+					"helm.templates":     attribute.IntValue(0),
+				}},
+				{Name: "Render", Status: codes.Error, Attrs: map[attribute.Key]attribute.Value{
+					"helm.chart.name":    attribute.StringValue("test-chart"),
+					"helm.chart.version": attribute.StringValue("0.1.0"),
+				}},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			recorder := tracetest.NewSpanRecorder()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+			chart, err := helm.LoadChartWithConfig(context.TODO(), chartFS, helm.LoadChartConfig{TracerProvider: tp})
+			require.NoError(err)
+
+			config := test.config
+			config.ReleaseName = "test"
+			config.Chart = chart
+			config.TracerProvider = tp
+			_, err = helm.Render(context.TODO(), config)
+			if test.expErr {
+				require.Error(err)
+			} else {
+				require.NoError(err)
+			}
+
+			spans := recorder.Ended()
+			names := map[[8]byte]string{}
+			for _, s := range spans {
+				names[s.SpanContext().SpanID()] = s.Name()
+			}
+
+			require.NotEmpty(spans)
+			load := spans[0]
+			assert.Equal("LoadChart", load.Name())
+			assert.Contains(load.Attributes(), attribute.String("helm.chart.name", "test-chart"))
+			assert.Contains(load.Attributes(), attribute.Int("helm.chart.files", 5))
+
+			gotSpans := []testSpan{}
+			for _, s := range spans[1:] {
+				attrs := map[attribute.Key]attribute.Value{}
+				for _, a := range s.Attributes() {
+					attrs[a.Key] = a.Value
+				}
+				gotSpans = append(gotSpans, testSpan{
+					Name:   s.Name(),
+					Parent: names[s.Parent().SpanID()],
+					Attrs:  attrs,
+					Status: s.Status().Code,
+				})
+			}
+			assert.Equal(test.expSpans, gotSpans)
+		})
+	}
+}
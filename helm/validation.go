@@ -0,0 +1,391 @@
+package helm
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	chartv2 "helm.sh/helm/v4/pkg/chart/v2"
+	apiextensionsscheme "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/scheme"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/managedfields"
+	"k8s.io/client-go/applyconfigurations"
+	kscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+	"sigs.k8s.io/structured-merge-diff/v6/typed"
+	"sigs.k8s.io/yaml"
+)
+
+// ValidationConfig is the configuration to validate the rendered manifests against the Kubernetes
+// schemas, without accessing any cluster.
+//
+// The resources are validated against (in order of precedence):
+//   - The CRD schemas of the chart (`crds/` directory).
+//   - The schemas of `SchemasFS`.
+//   - The bundled schemas of the Kubernetes builtin resources.
+type ValidationConfig struct {
+	// SchemasFS is an optional fs.FS with Kubernetes OpenAPI v2 (`definitions`) or v3 (`components.schemas`)
+	// JSON documents, like the ones served by the Kubernetes API (e.g: `kubectl get --raw /openapi/v2`).
+	// The documents must be on a directory per Kubernetes version (e.g: `v1.29/swagger.json`) selected
+	// with `KubeVersion`.
+	SchemasFS fs.FS
+	// KubeVersion is the Kubernetes version (e.g: `1.29`) used to select the `SchemasFS` documents directory.
+	// If empty, the root of `SchemasFS` will be used. The bundled schemas are not versioned, so it
+	// requires `SchemasFS`.
+	KubeVersion string
+	// IgnoreMissingSchemas when enabled will not fail on resources that don't have a schema
+	// (e.g: Custom resources without CRD on the chart), by default it will fail.
+	IgnoreMissingSchemas bool
+}
+
+func (c ValidationConfig) validate() error {
+	if c.KubeVersion != "" && c.SchemasFS == nil {
+		return fmt.Errorf("kube version %q requires the schemas FS, the bundled schemas are not versioned", c.KubeVersion)
+	}
+
+	return nil
+}
+
+// ValidationFailure is a rendered resource validation failure.
+type ValidationFailure struct {
+	// Source is the template that rendered the resource (e.g: `mychart/templates/deployment.yaml`).
+	Source string
+	// APIVersion is the apiVersion of the resource.
+	APIVersion string
+	// Kind is the kind of the resource.
+	Kind string
+	// Namespace is the namespace of the resource.
+	Namespace string
+	// Name is the name of the resource.
+	Name string
+	// Field is the path of the invalid field (e.g: `.spec.template.spec.contianers`), empty if
+	// the failure is not related with a specific field.
+	Field string
+	// Message is the description of the failure.
+	Message string
+}
+
+func (f ValidationFailure) String() string {
+	id := f.Name
+	if f.Namespace != "" {
+		id = f.Namespace + "/" + f.Name
+	}
+
+	msg := fmt.Sprintf("%s: %s %s %s: ", f.Source, f.APIVersion, f.Kind, id)
+	if f.Field != "" {
+		msg += f.Field + ": "
+	}
+
+	return msg + f.Message
+}
+
+// ValidationError is the error returned when the rendered manifests are not valid.
+type ValidationError struct {
+	// Failures are all the validation failures of the rendered resources.
+	Failures []ValidationFailure
+}
+
+func (e *ValidationError) Error() string {
+	failures := make([]string, 0, len(e.Failures))
+	for _, f := range e.Failures {
+		failures = append(failures, f.String())
+	}
+
+	return fmt.Sprintf("%d schema validation failures: %s", len(e.Failures), strings.Join(failures, "; "))
+}
+
+type bundledSchemas struct {
+	scheme *runtime.Scheme
+	// typeConverter is the schemas type converter, if missing the resources will be validated
+	// with a strict decoding of the scheme types.
+	typeConverter managedfields.TypeConverter
+}
+
+// bundledTypeConverters returns the Kubernetes builtin resources schemas (including CRDs) bundled
+// with the Kubernetes client libraries.
+var bundledTypeConverters = sync.OnceValue(func() []bundledSchemas {
+	return []bundledSchemas{
+		// The CRDs client library doesn't have the schemas. Goes first because the CRDs could be
+		// registered on the Kubernetes client library scheme.
+		{scheme: apiextensionsscheme.Scheme},
+		{scheme: kscheme.Scheme, typeConverter: applyconfigurations.NewTypeConverter(kscheme.Scheme)},
+	}
+})
+
+type schemaValidator struct {
+	custom               managedfields.TypeConverter
+	customKinds          map[schema.GroupVersionKind]bool
+	ignoreMissingSchemas bool
+}
+
+func newSchemaValidator(chart *chartv2.Chart, config ValidationConfig) (*schemaValidator, error) {
+	models := map[string]*spec.Schema{}
+
+	if config.SchemasFS != nil {
+		err := loadOpenAPIModels(config.SchemasFS, config.KubeVersion, models)
+		if err != nil {
+			return nil, fmt.Errorf("could not load schemas: %w", err)
+		}
+	}
+
+	// Chart CRDs have precedence.
+	for _, crd := range chart.CRDObjects() {
+		err := loadCRDModels(crd.File.Data, models)
+		if err != nil {
+			return nil, fmt.Errorf("could not load CRD %q schemas: %w", crd.Filename, err)
+		}
+	}
+
+	customKinds := map[schema.GroupVersionKind]bool{}
+	for _, m := range models {
+		for _, gvk := range modelGVKs(m) {
+			customKinds[gvk] = true
+		}
+	}
+
+	v := &schemaValidator{
+		customKinds:          customKinds,
+		ignoreMissingSchemas: config.IgnoreMissingSchemas,
+	}
+	if len(customKinds) > 0 {
+		tc, err := managedfields.NewTypeConverter(models, false)
+		if err != nil {
+			return nil, fmt.Errorf("could not prepare schemas: %w", err)
+		}
+		v.custom = tc
+	}
+
+	return v, nil
+}
+
+// validate validates all the resources of the rendered manifests.
+func (v *schemaValidator) validate(manifests string) []ValidationFailure {
+	failures := []ValidationFailure{}
+	for _, m := range splitRenderedManifests(manifests) {
+		obj := map[string]interface{}{}
+		err := yaml.Unmarshal([]byte(m.content), &obj)
+		if err != nil {
+			failures = append(failures, ValidationFailure{Source: m.source, Message: fmt.Sprintf("invalid YAML: %s", err)})
+			continue
+		}
+
+		// Empty documents (e.g: only comments).
+		if len(obj) == 0 {
+			continue
+		}
+
+		failures = append(failures, v.validateObject(m.source, &unstructured.Unstructured{Object: obj})...)
+	}
+
+	return failures
+}
+
+func (v *schemaValidator) validateObject(source string, obj *unstructured.Unstructured) []ValidationFailure {
+	newFailure := func(field, msg string) ValidationFailure {
+		return ValidationFailure{
+			Source:     source,
+			APIVersion: obj.GetAPIVersion(),
+			Kind:       obj.GetKind(),
+			Namespace:  obj.GetNamespace(),
+			Name:       obj.GetName(),
+			Field:      field,
+			Message:    msg,
+		}
+	}
+
+	gvk := obj.GroupVersionKind()
+	if gvk.Version == "" || gvk.Kind == "" {
+		return []ValidationFailure{newFailure("", "apiVersion and kind are required")}
+	}
+
+	var bundled *bundledSchemas
+	if !v.customKinds[gvk] {
+		for _, b := range bundledTypeConverters() {
+			if b.scheme.Recognizes(gvk) {
+				bundled = &b
+				break
+			}
+		}
+
+		if bundled == nil {
+			if v.ignoreMissingSchemas {
+				return nil
+			}
+			return []ValidationFailure{newFailure("", "schema not found")}
+		}
+
+		if bundled.typeConverter == nil {
+			err := strictDecode(bundled.scheme, gvk, obj)
+			if err != nil {
+				return []ValidationFailure{newFailure("", err.Error())}
+			}
+			return nil
+		}
+	}
+
+	tc := v.custom
+	if bundled != nil {
+		tc = bundled.typeConverter
+	}
+
+	_, err := tc.ObjectToTyped(obj)
+	if err == nil {
+		return nil
+	}
+
+	verrs, ok := err.(typed.ValidationErrors)
+	if !ok {
+		return []ValidationFailure{newFailure("", err.Error())}
+	}
+
+	failures := make([]ValidationFailure, 0, len(verrs))
+	for _, verr := range verrs {
+		failures = append(failures, newFailure(verr.Path, verr.ErrorMessage))
+	}
+
+	// The validation errors order is not stable.
+	slices.SortFunc(failures, func(a, b ValidationFailure) int {
+		return cmp.Or(cmp.Compare(a.Field, b.Field), cmp.Compare(a.Message, b.Message))
+	})
+
+	return failures
+}
+
+// strictDecode decodes the object into its scheme type failing on unknown fields and invalid types.
+func strictDecode(scheme *runtime.Scheme, gvk schema.GroupVersionKind, obj *unstructured.Unstructured) error {
+	typedObj, err := scheme.New(gvk)
+	if err != nil {
+		return err
+	}
+
+	data, err := obj.MarshalJSON()
+	if err != nil {
+		return err
+	}
+
+	return yaml.UnmarshalStrict(data, typedObj)
+}
+
+// loadOpenAPIModels loads the schema models of all the OpenAPI JSON documents on the Kubernetes version
+// directory of the fs.FS.
+func loadOpenAPIModels(f fs.FS, kubeVersion string, models map[string]*spec.Schema) error {
+	dir := "."
+	if kubeVersion != "" {
+		dir = "v" + strings.TrimPrefix(kubeVersion, "v")
+	}
+
+	files, err := fs.Glob(f, path.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("missing schemas for Kubernetes version %q", kubeVersion)
+	}
+
+	for _, file := range files {
+		data, err := fs.ReadFile(f, file)
+		if err != nil {
+			return fmt.Errorf("could not read %q: %w", file, err)
+		}
+
+		doc := struct {
+			Definitions map[string]*spec.Schema `json:"definitions"`
+			Components  struct {
+				Schemas map[string]*spec.Schema `json:"schemas"`
+			} `json:"components"`
+		}{}
+		err = json.Unmarshal(data, &doc)
+		if err != nil {
+			return fmt.Errorf("could not unmarshal %q OpenAPI document: %w", file, err)
+		}
+
+		for k, v := range doc.Definitions {
+			models[k] = v
+		}
+		for k, v := range doc.Components.Schemas {
+			models[k] = v
+		}
+	}
+
+	return nil
+}
+
+// loadCRDModels loads the schema models of each of the CRD versions.
+func loadCRDModels(data []byte, models map[string]*spec.Schema) error {
+	for _, doc := range splitMarkRe.Split(string(data), -1) {
+		crd := struct {
+			APIVersion string `json:"apiVersion"`
+			Kind       string `json:"kind"`
+			Spec       struct {
+				Group string `json:"group"`
+				Names struct {
+					Kind string `json:"kind"`
+				} `json:"names"`
+				Versions []struct {
+					Name   string `json:"name"`
+					Schema *struct {
+						OpenAPIV3Schema *spec.Schema `json:"openAPIV3Schema"`
+					} `json:"schema"`
+				} `json:"versions"`
+			} `json:"spec"`
+		}{}
+		err := yaml.Unmarshal([]byte(doc), &crd)
+		if err != nil {
+			return err
+		}
+
+		// Only `apiextensions.k8s.io/v1` CRDs have schemas per version.
+		if crd.APIVersion != "apiextensions.k8s.io/v1" || crd.Kind != "CustomResourceDefinition" {
+			continue
+		}
+
+		for _, v := range crd.Spec.Versions {
+			if v.Schema == nil || v.Schema.OpenAPIV3Schema == nil {
+				continue
+			}
+
+			s := v.Schema.OpenAPIV3Schema
+			// The API server adds the common fields of all the resources.
+			if s.Properties == nil {
+				s.Properties = map[string]spec.Schema{}
+			}
+			s.Properties["apiVersion"] = *spec.StringProperty()
+			s.Properties["kind"] = *spec.StringProperty()
+			metadata := spec.MapProperty(nil)
+			metadata.AddExtension("x-kubernetes-preserve-unknown-fields", true)
+			s.Properties["metadata"] = *metadata
+
+			s.AddExtension("x-kubernetes-group-version-kind", []interface{}{
+				map[string]interface{}{"group": crd.Spec.Group, "version": v.Name, "kind": crd.Spec.Names.Kind},
+			})
+			models[fmt.Sprintf("crd.%s.%s.%s", crd.Spec.Group, v.Name, crd.Spec.Names.Kind)] = s
+		}
+	}
+
+	return nil
+}
+
+// modelGVKs returns the Kubernetes resources (group, version, kind) of a schema model.
+func modelGVKs(m *spec.Schema) []schema.GroupVersionKind {
+	gvks := []schema.GroupVersionKind{}
+	list, _ := m.Extensions["x-kubernetes-group-version-kind"].([]interface{})
+	for _, e := range list {
+		gvk, ok := e.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		group, _ := gvk["group"].(string)
+		version, _ := gvk["version"].(string)
+		kind, _ := gvk["kind"].(string)
+		gvks = append(gvks, schema.GroupVersionKind{Group: group, Version: version, Kind: kind})
+	}
+
+	return gvks
+}
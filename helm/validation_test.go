@@ -0,0 +1,228 @@
+package helm_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/go-helm-template/helm"
+)
+
+func newTestValidationChart() *helm.Chart {
+	chartFS := newTestChartFS()
+	chartFS["crds/foo.yaml"] = &fstest.MapFile{Data: []byte(`
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: foos.example.com
+spec:
+  group: example.com
+  names:
+    kind: Foo
+    plural: foos
+  scope: Namespaced
+  versions:
+  - name: v1
+    served: true
+    storage: true
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            type: object
+            properties:
+              size:
+                type: integer
+`)}
+	chartFS["templates/resources.yaml"] = &fstest.MapFile{Data: []byte(`
+{{- range .Values.resources }}
+---
+{{ toYaml . }}
+{{- end }}
+`)}
+
+	return mustLoadChart(chartFS)
+}
+
+const testValidationSwagger = `{
+  "definitions": {
+    "com.example.v1.Bar": {
+      "type": "object",
+      "properties": {
+        "apiVersion": {"type": "string"},
+        "kind": {"type": "string"},
+        "metadata": {"$ref": "#/definitions/com.example.v1.Meta"},
+        "replicas": {"type": "integer"}
+      },
+      "x-kubernetes-group-version-kind": [{"group": "example.com", "version": "v1", "kind": "Bar"}]
+    },
+    "com.example.v1.Meta": {
+      "type": "object",
+      "properties": {
+        "name": {"type": "string"}
+      }
+    }
+  }
+}`
+
+func TestTemplateValidation(t *testing.T) {
+	tests := map[string]struct {
+		resources   []interface{}
+		includeCRDs bool
+		validation  helm.ValidationConfig
+		expErr      bool
+		expFailures []helm.ValidationFailure
+	}{
+		"Valid builtin resources should not fail.": {
+			resources: []interface{}{
+				map[string]interface{}{"apiVersion": "v1", "kind": "ConfigMap", "metadata": map[string]interface{}{"name": "test"}, "data": map[string]interface{}{"a": "b"}},
+			},
+		},
+
+		"Invalid builtin resources should fail with the invalid fields.": {
+			resources: []interface{}{
+				map[string]interface{}{"apiVersion": "v1", "kind": "ConfigMap", "metadata": map[string]interface{}{"name": "test"}},
+				map[string]interface{}{
+					"apiVersion": "apps/v1",
+					"kind":       "Deployment",
+					"metadata":   map[string]interface{}{"name": "test", "namespace": "test-ns"},
+					"spec": map[string]interface{}{
+						"replicas": "3",
+						"template": map[string]interface{}{
+							"spec": map[string]interface{}{"contianers": []interface{}{}},
+						},
+					},
+				},
+			},
+			expErr: true,
+			expFailures: []helm.ValidationFailure{
+				{Source: "test-chart/templates/resources.yaml", APIVersion: "apps/v1", Kind: "Deployment", Namespace: "test-ns", Name: "test", Field: ".spec.replicas", Message: "expected numeric (int or float), got string"},
+				{Source: "test-chart/templates/resources.yaml", APIVersion: "apps/v1", Kind: "Deployment", Namespace: "test-ns", Name: "test", Field: ".spec.template.spec.contianers", Message: "field not declared in schema"},
+			},
+		},
+
+		"Resources without apiVersion or kind should fail.": {
+			resources: []interface{}{
+				map[string]interface{}{"metadata": map[string]interface{}{"name": "test"}},
+			},
+			expErr: true,
+			expFailures: []helm.ValidationFailure{
+				{Source: "test-chart/templates/resources.yaml", Name: "test", Message: "apiVersion and kind are required"},
+			},
+		},
+
+		"Valid custom resources with the chart CRDs should not fail.": {
+			resources: []interface{}{
+				map[string]interface{}{"apiVersion": "example.com/v1", "kind": "Foo", "metadata": map[string]interface{}{"name": "test"}, "spec": map[string]interface{}{"size": 1}},
+			},
+		},
+
+		"The chart CRDs should be validated when included.": {
+			includeCRDs: true,
+			resources: []interface{}{
+				map[string]interface{}{"apiVersion": "example.com/v1", "kind": "Foo", "metadata": map[string]interface{}{"name": "test"}},
+			},
+		},
+
+		"Invalid CRDs should fail.": {
+			resources: []interface{}{
+				map[string]interface{}{"apiVersion": "apiextensions.k8s.io/v1", "kind": "CustomResourceDefinition", "metadata": map[string]interface{}{"name": "test"}, "spec": map[string]interface{}{"grup": "test"}},
+			},
+			expErr: true,
+			expFailures: []helm.ValidationFailure{
+				{Source: "test-chart/templates/resources.yaml", APIVersion: "apiextensions.k8s.io/v1", Kind: "CustomResourceDefinition", Name: "test", Message: `error unmarshaling JSON: while decoding JSON: json: unknown field "grup"`},
+			},
+		},
+
+		"Invalid custom resources with the chart CRDs should fail.": {
+			resources: []interface{}{
+				map[string]interface{}{"apiVersion": "example.com/v1", "kind": "Foo", "metadata": map[string]interface{}{"name": "test"}, "spec": map[string]interface{}{"size": "big"}},
+			},
+			expErr: true,
+			expFailures: []helm.ValidationFailure{
+				{Source: "test-chart/templates/resources.yaml", APIVersion: "example.com/v1", Kind: "Foo", Name: "test", Field: ".spec.size", Message: "expected numeric (int or float), got string"},
+			},
+		},
+
+		"Resources without schema should fail.": {
+			resources: []interface{}{
+				map[string]interface{}{"apiVersion": "example.com/v1", "kind": "Bar", "metadata": map[string]interface{}{"name": "test"}},
+			},
+			expErr: true,
+			expFailures: []helm.ValidationFailure{
+				{Source: "test-chart/templates/resources.yaml", APIVersion: "example.com/v1", Kind: "Bar", Name: "test", Message: "schema not found"},
+			},
+		},
+
+		"Resources without schema should not fail if ignored.": {
+			resources: []interface{}{
+				map[string]interface{}{"apiVersion": "example.com/v1", "kind": "Bar", "metadata": map[string]interface{}{"name": "test"}},
+			},
+			validation: helm.ValidationConfig{IgnoreMissingSchemas: true},
+		},
+
+		"Resources should be validated with the custom schemas of the Kubernetes version.": {
+			resources: []interface{}{
+				map[string]interface{}{"apiVersion": "example.com/v1", "kind": "Bar", "metadata": map[string]interface{}{"name": "test"}, "replicas": 1},
+				map[string]interface{}{"apiVersion": "example.com/v1", "kind": "Bar", "metadata": map[string]interface{}{"name": "test2"}, "replicas": "1"},
+			},
+			validation: helm.ValidationConfig{
+				KubeVersion: "1.29",
+				SchemasFS: fstest.MapFS{
+					"v1.29/swagger.json": &fstest.MapFile{Data: []byte(testValidationSwagger)},
+				},
+			},
+			expErr: true,
+			expFailures: []helm.ValidationFailure{
+				{Source: "test-chart/templates/resources.yaml", APIVersion: "example.com/v1", Kind: "Bar", Name: "test2", Field: ".replicas", Message: "expected numeric (int or float), got string"},
+			},
+		},
+
+		"Missing custom schemas for the Kubernetes version should fail.": {
+			resources: []interface{}{},
+			validation: helm.ValidationConfig{
+				KubeVersion: "1.30",
+				SchemasFS: fstest.MapFS{
+					"v1.29/swagger.json": &fstest.MapFile{Data: []byte(testValidationSwagger)},
+				},
+			},
+			expErr: true,
+		},
+
+		"A Kubernetes version without custom schemas should fail.": {
+			resources:  []interface{}{},
+			validation: helm.ValidationConfig{KubeVersion: "1.29"},
+			expErr:     true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			validation := test.validation
+			_, err := helm.Template(context.TODO(), helm.TemplateConfig{
+				ReleaseName: "test",
+				Chart:       newTestValidationChart(),
+				Values:      map[string]interface{}{"resources": test.resources},
+				IncludeCRDs: test.includeCRDs,
+				Validation:  &validation,
+			})
+
+			if test.expErr {
+				if assert.Error(err) && test.expFailures != nil {
+					var verr *helm.ValidationError
+					if assert.True(errors.As(err, &verr)) {
+						assert.Equal(test.expFailures, verr.Failures)
+					}
+				}
+			} else {
+				assert.NoError(err)
+			}
+		})
+	}
+}
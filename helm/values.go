@@ -0,0 +1,224 @@
+package helm
+
+import (
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template/parse"
+	"unsafe"
+)
+
+// valuesPaths knows the values path of the rendered values maps by their identity, so the maps
+// referenced on the templates (e.g: `.`, `$v`) can be resolved to their values path.
+type valuesPaths map[unsafe.Pointer][]fieldPathSegment
+
+// index stores the values path of all the values maps.
+func (p valuesPaths) index(v reflect.Value, path []fieldPathSegment) {
+	v = indirectValue(v)
+	switch {
+	case !v.IsValid():
+	case v.Kind() == reflect.Map:
+		if _, ok := p[v.UnsafePointer()]; ok || v.UnsafePointer() == nil {
+			return
+		}
+		p[v.UnsafePointer()] = path
+
+		iter := v.MapRange()
+		for iter.Next() {
+			p.index(iter.Value(), append(slices.Clip(path), fieldPathSegment{key: iter.Key().String()}))
+		}
+	case v.Kind() == reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			p.index(v.Index(i), append(slices.Clip(path), fieldPathSegment{index: i, isIndex: true}))
+		}
+	}
+}
+
+// resolve returns the values path of the fields accessed from a template value, the path until the
+// first missing key (if any), and false if the template value is not part of the values.
+func (p valuesPaths) resolve(base interface{}, fields []string) (path, missing []fieldPathSegment, ok bool) {
+	v := reflect.ValueOf(base)
+	for i := 0; ; i++ {
+		v = indirectValue(v)
+		if v.IsValid() && v.Kind() == reflect.Map {
+			if mp, found := p[v.UnsafePointer()]; found {
+				path = slices.Clone(mp)
+				ok = true
+			}
+		}
+
+		if i == len(fields) {
+			break
+		}
+
+		f := fields[i]
+		if ok {
+			path = append(path, fieldPathSegment{key: f})
+		}
+
+		if !v.IsValid() || v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+			// Not values data, keep the path for missing keys (e.g: typos).
+			v = reflect.Value{}
+			continue
+		}
+
+		v = v.MapIndex(reflect.ValueOf(f).Convert(v.Type().Key()))
+		if !v.IsValid() && ok && missing == nil {
+			missing = slices.Clone(path)
+		}
+	}
+
+	return path, missing, ok
+}
+
+// instrumentValuesRefs adds the nodes returned by the instrument function before all the template
+// nodes that reference values.
+func instrumentValuesRefs(list *parse.ListNode, instrument func(n parse.Node, refs []valueRef) []parse.Node) {
+	if list == nil {
+		return
+	}
+
+	nodes := make([]parse.Node, 0, len(list.Nodes))
+	for _, n := range list.Nodes {
+		var pipe *parse.PipeNode
+		switch n := n.(type) {
+		case *parse.ActionNode:
+			if !isInstrumentationNode(n) {
+				pipe = n.Pipe
+			}
+		case *parse.TemplateNode:
+			pipe = n.Pipe
+		case *parse.IfNode:
+			pipe = n.Pipe
+			instrumentValuesRefs(n.List, instrument)
+			instrumentValuesRefs(n.ElseList, instrument)
+		case *parse.WithNode:
+			pipe = n.Pipe
+			instrumentValuesRefs(n.List, instrument)
+			instrumentValuesRefs(n.ElseList, instrument)
+		case *parse.RangeNode:
+			pipe = n.Pipe
+			instrumentValuesRefs(n.List, instrument)
+			instrumentValuesRefs(n.ElseList, instrument)
+		}
+
+		if refs := pipeValueRefs(pipe); len(refs) > 0 {
+			nodes = append(nodes, instrument(n, refs)...)
+		}
+		nodes = append(nodes, n)
+	}
+	list.Nodes = nodes
+}
+
+type valueRef struct {
+	// base is the node (dot or variable) where the fields are accessed.
+	base   parse.Node
+	fields []string
+	// index is true when the fields are accessed with `index` instead of a field chain.
+	index bool
+}
+
+// pipeValueRefs returns the template values referenced on a pipeline.
+func pipeValueRefs(pipe *parse.PipeNode) []valueRef {
+	if pipe == nil {
+		return nil
+	}
+
+	refs := []valueRef{}
+	for _, cmd := range pipe.Cmds {
+		// `index` with static keys is like a field access.
+		if ref, ok := indexValueRef(cmd); ok {
+			refs = append(refs, ref)
+			continue
+		}
+
+		for _, arg := range cmd.Args {
+			refs = append(refs, nodeValueRefs(arg)...)
+		}
+	}
+
+	return refs
+}
+
+func nodeValueRefs(n parse.Node) []valueRef {
+	switch n := n.(type) {
+	case *parse.DotNode:
+		return []valueRef{{base: n}}
+	case *parse.FieldNode:
+		return []valueRef{{base: &parse.DotNode{NodeType: parse.NodeDot, Pos: n.Pos}, fields: n.Ident}}
+	case *parse.VariableNode:
+		base := n.Copy().(*parse.VariableNode)
+		base.Ident = base.Ident[:1]
+		return []valueRef{{base: base, fields: n.Ident[1:]}}
+	case *parse.PipeNode:
+		return pipeValueRefs(n)
+	case *parse.ChainNode:
+		return nodeValueRefs(n.Node)
+	}
+
+	return nil
+}
+
+func indexValueRef(cmd *parse.CommandNode) (valueRef, bool) {
+	if len(cmd.Args) < 3 {
+		return valueRef{}, false
+	}
+
+	if id, ok := cmd.Args[0].(*parse.IdentifierNode); !ok || id.Ident != "index" {
+		return valueRef{}, false
+	}
+
+	refs := nodeValueRefs(cmd.Args[1])
+	if len(refs) != 1 {
+		return valueRef{}, false
+	}
+
+	ref := refs[0]
+	ref.fields = slices.Clone(ref.fields)
+	ref.index = true
+	for _, arg := range cmd.Args[2:] {
+		s, ok := arg.(*parse.StringNode)
+		if !ok {
+			return valueRef{}, false
+		}
+		ref.fields = append(ref.fields, s.Text)
+	}
+
+	return ref, true
+}
+
+// newValuesFuncCallNode returns an action that calls a function with the static string arguments,
+// the referenced template value and its fields (e.g: `{{ fn "arg" $v "a" "b" }}`).
+func newValuesFuncCallNode(funcName string, args []string, ref valueRef) parse.Node {
+	src := []string{funcName}
+	for _, a := range args {
+		src = append(src, strconv.Quote(a))
+	}
+	src = append(src, ".")
+	for _, f := range ref.fields {
+		src = append(src, strconv.Quote(f))
+	}
+
+	trees, err := parseTemplate(funcName, []byte("{{ "+strings.Join(src, " ")+" }}"))
+	if err != nil {
+		// Should never happen, it's a generated template.
+		panic(err)
+	}
+
+	action := trees[funcName].Root.Nodes[0].(*parse.ActionNode)
+	action.Pipe.Cmds[0].Args[len(args)+1] = ref.base
+
+	return action
+}
+
+func indirectValue(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Interface || v.Kind() == reflect.Pointer) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+
+	return v
+}
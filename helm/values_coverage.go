@@ -0,0 +1,218 @@
+package helm
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"helm.sh/helm/v4/pkg/chart/common"
+	chartv2 "helm.sh/helm/v4/pkg/chart/v2"
+)
+
+const (
+	valuesTrackFuncName = "goHelmTemplateTrackValues"
+
+	// valuesAccessNode is used when only the value itself is used (e.g: `if`, `with`).
+	valuesAccessNode = "node"
+	// valuesAccessSubtree is used when the value and all its children are used (e.g: `toYaml`, `range`).
+	valuesAccessSubtree = "subtree"
+
+	globalValuesKey = "global"
+)
+
+// ValuesCoverage is the report of the values used while rendering a chart.
+type ValuesCoverage struct {
+	// Charts are the values coverage of the chart and each of its subcharts, the chart first
+	// and then the subcharts sorted.
+	Charts []ChartValuesCoverage
+}
+
+// ChartValuesCoverage is the values coverage of a chart.
+type ChartValuesCoverage struct {
+	// Chart is the path of the chart (e.g: `mychart`, `mychart/charts/sub`).
+	Chart string
+	// UnusedDefaultKeys are the keys of the chart default values (`values.yaml`) that have not been
+	// used while rendering (e.g: `image.tag`, `labels["app.kubernetes.io/name"]`).
+	UnusedDefaultKeys []string
+	// UnusedUserKeys are the keys of the user provided values for this chart that have not been
+	// used while rendering (e.g: typos like `replicaCont`).
+	UnusedUserKeys []string
+}
+
+// valuesTracker is a render instrument that records the values paths accessed by the templates.
+//
+// Templates are instrumented to call a tracking function before each action with the values
+// references used by the action, the referenced maps are resolved to their values path using
+// the identity of the rendered values maps.
+//
+// Note: The values accessed inside `tpl` templates are not tracked.
+type valuesTracker struct {
+	chart    *chartv2.Chart
+	paths    valuesPaths
+	accessed map[string]string
+}
+
+func newValuesTracker() *valuesTracker {
+	return &valuesTracker{
+		paths:    valuesPaths{},
+		accessed: map[string]string{},
+	}
+}
+
+func (t *valuesTracker) instrumentTemplate(fullName string, source []byte, trees map[string]*parse.Tree) error {
+	for _, tree := range trees {
+		instrumentValuesTracking(tree.Root)
+	}
+
+	return nil
+}
+
+func (t *valuesTracker) funcs() template.FuncMap {
+	return template.FuncMap{valuesTrackFuncName: t.track}
+}
+
+func (t *valuesTracker) prepareValues(chart *chartv2.Chart, values common.Values) {
+	t.chart = chart
+	t.paths.index(reflect.ValueOf(values["Values"]), nil)
+}
+
+// track is the template function that records the values accessed from a template value (e.g: `.`, `$v`)
+// and its fields.
+func (t *valuesTracker) track(mode string, base interface{}, fields ...string) string {
+	path, _, ok := t.paths.resolve(base, fields)
+	if ok {
+		t.record(path, mode)
+	}
+
+	return ""
+}
+
+func (t *valuesTracker) record(path []fieldPathSegment, mode string) {
+	key := formatFieldPath(path)
+	if t.accessed[key] != valuesAccessSubtree {
+		t.accessed[key] = mode
+	}
+
+	// Subcharts have a copy of the global values.
+	for i, s := range path {
+		if i > 0 && !s.isIndex && s.key == globalValuesKey {
+			t.record(path[i:], mode)
+			break
+		}
+	}
+}
+
+// isUsed returns true if the values path has been used by itself, by any of its parents (as a
+// whole subtree) or by any of its children.
+func (t *valuesTracker) isUsed(path []fieldPathSegment) bool {
+	key := formatFieldPath(path)
+	if _, ok := t.accessed[key]; ok {
+		return true
+	}
+
+	for i := 0; i < len(path); i++ {
+		if t.accessed[formatFieldPath(path[:i])] == valuesAccessSubtree {
+			return true
+		}
+	}
+
+	for k := range t.accessed {
+		if strings.HasPrefix(k, key+".") || strings.HasPrefix(k, key+"[") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// coverage returns the values coverage of the rendered chart and its subcharts.
+func (t *valuesTracker) coverage(userValues map[string]interface{}) *ValuesCoverage {
+	cov := &ValuesCoverage{Charts: []ChartValuesCoverage{}}
+	if t.chart == nil {
+		return cov
+	}
+
+	var addChart func(chart *chartv2.Chart, prefix []fieldPathSegment, user interface{})
+	addChart = func(chart *chartv2.Chart, prefix []fieldPathSegment, user interface{}) {
+		subcharts := map[string]bool{}
+		for _, dep := range chart.Dependencies() {
+			subcharts[dep.Name()] = true
+		}
+
+		c := ChartValuesCoverage{
+			Chart:             chart.ChartFullPath(),
+			UnusedDefaultKeys: t.unusedKeys(chart.Values, prefix, nil),
+			UnusedUserKeys:    t.unusedKeys(user, prefix, subcharts),
+		}
+		cov.Charts = append(cov.Charts, c)
+
+		deps := slices.Clone(chart.Dependencies())
+		slices.SortFunc(deps, func(a, b *chartv2.Chart) int { return strings.Compare(a.Name(), b.Name()) })
+		for _, dep := range deps {
+			var depUser interface{}
+			if m, ok := user.(map[string]interface{}); ok {
+				depUser = m[dep.Name()]
+			}
+			addChart(dep, append(slices.Clip(prefix), fieldPathSegment{key: dep.Name()}), depUser)
+		}
+	}
+	addChart(t.chart, nil, userValues)
+
+	return cov
+}
+
+// unusedKeys returns the unused values keys (leafs) relative to the chart, the keys of the
+// ignored (first level) keys will not be checked.
+func (t *valuesTracker) unusedKeys(values interface{}, prefix []fieldPathSegment, ignore map[string]bool) []string {
+	unused := []string{}
+
+	var walk func(v interface{}, path []fieldPathSegment)
+	walk = func(v interface{}, path []fieldPathSegment) {
+		if m, ok := v.(map[string]interface{}); ok && len(m) > 0 {
+			for k, mv := range m {
+				if len(path) == 0 && ignore[k] {
+					continue
+				}
+				walk(mv, append(slices.Clip(path), fieldPathSegment{key: k}))
+			}
+			return
+		}
+
+		if len(path) > 0 && !t.isUsed(append(slices.Clip(prefix), path...)) {
+			unused = append(unused, formatFieldPath(path))
+		}
+	}
+
+	if m, ok := values.(common.Values); ok {
+		values = map[string]interface{}(m)
+	}
+	walk(values, nil)
+	slices.Sort(unused)
+
+	return unused
+}
+
+// instrumentValuesTracking adds values tracking actions before all the nodes that reference values.
+func instrumentValuesTracking(list *parse.ListNode) {
+	instrumentValuesRefs(list, func(n parse.Node, refs []valueRef) []parse.Node {
+		mode := valuesAccessSubtree
+		switch n := n.(type) {
+		case *parse.ActionNode:
+			// Values assigned to variables will be tracked when the variables are used.
+			if len(n.Pipe.Decl) > 0 {
+				mode = valuesAccessNode
+			}
+		case *parse.IfNode, *parse.WithNode:
+			mode = valuesAccessNode
+		}
+
+		nodes := make([]parse.Node, 0, len(refs))
+		for _, ref := range refs {
+			nodes = append(nodes, newValuesFuncCallNode(valuesTrackFuncName, []string{mode}, ref))
+		}
+
+		return nodes
+	})
+}
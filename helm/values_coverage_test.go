@@ -0,0 +1,145 @@
+package helm_test
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/go-helm-template/helm"
+)
+
+func TestRenderValuesCoverage(t *testing.T) {
+	tests := map[string]struct {
+		chart       func() *helm.Chart
+		values      map[string]interface{}
+		expCoverage *helm.ValuesCoverage
+	}{
+		"Used values should not be reported as unused.": {
+			chart: func() *helm.Chart {
+				chartFS := newTestChartFS()
+				chartFS["values.yaml"] = &fstest.MapFile{Data: []byte(`
+replicaCount: 1
+image:
+  repository: app
+  tag: v1
+  pullPolicy: Always
+labels:
+  app.kubernetes.io/name: test
+annotations:
+  a: b
+ports:
+- 80
+- 443
+enabled: true
+unused:
+  a: b
+`)}
+				chartFS["templates/cm.yaml"] = &fstest.MapFile{Data: []byte(`
+{{- $image := .Values.image }}
+replicas: {{ .Values.replicaCount }}
+image: {{ $image.repository }}:{{ index .Values.image "tag" }}
+{{- if .Values.enabled }}
+labels: {{ toYaml .Values.labels | nindent 2 }}
+{{- end }}
+{{- with .Values.annotations }}
+annotations: {{ toYaml . | nindent 2 }}
+{{- end }}
+ports:
+{{- range .Values.ports }}
+- {{ . }}
+{{- end }}
+`)}
+				return mustLoadChart(chartFS)
+			},
+			expCoverage: &helm.ValuesCoverage{Charts: []helm.ChartValuesCoverage{
+				{
+					Chart:             "test-chart",
+					UnusedDefaultKeys: []string{"image.pullPolicy", "unused.a"},
+					UnusedUserKeys:    []string{},
+				},
+			}},
+		},
+
+		"Unused user values (e.g: typos) should be reported.": {
+			chart: func() *helm.Chart {
+				chartFS := newTestChartFS()
+				chartFS["values.yaml"] = &fstest.MapFile{Data: []byte("replicaCount: 1\nimage:\n  tag: v1\n")}
+				chartFS["templates/cm.yaml"] = &fstest.MapFile{Data: []byte(`
+replicas: {{ .Values.replicaCount }}
+tag: {{ .Values.image.tag }}
+`)}
+				return mustLoadChart(chartFS)
+			},
+			values: map[string]interface{}{
+				"replicaCont": 3,
+				"image":       map[string]interface{}{"tag": "v2", "tga": "v3"},
+			},
+			expCoverage: &helm.ValuesCoverage{Charts: []helm.ChartValuesCoverage{
+				{
+					Chart:             "test-chart",
+					UnusedDefaultKeys: []string{},
+					UnusedUserKeys:    []string{"image.tga", "replicaCont"},
+				},
+			}},
+		},
+
+		"Subcharts should have their own coverage.": {
+			chart: func() *helm.Chart {
+				chartFS := newTestChartFS()
+				chartFS["values.yaml"] = &fstest.MapFile{Data: []byte("global:\n  domain: example.com\nname: root\nsub1:\n  name: sub\n")}
+				chartFS["templates/cm.yaml"] = &fstest.MapFile{Data: []byte(`name: {{ .Values.name }}`)}
+				chartFS["charts/sub1/Chart.yaml"] = &fstest.MapFile{Data: []byte("apiVersion: v2\nname: sub1\nversion: 0.1.0")}
+				chartFS["charts/sub1/values.yaml"] = &fstest.MapFile{Data: []byte("name: default\nport: 80\n")}
+				chartFS["charts/sub1/templates/cm.yaml"] = &fstest.MapFile{Data: []byte(`
+name: {{ .Values.name }}
+domain: {{ .Values.global.domain }}
+{{ include "sub1.port" . }}
+`)}
+				chartFS["charts/sub1/templates/_helpers.tpl"] = &fstest.MapFile{Data: []byte(`{{- define "sub1.port" -}}port: {{ .Values.port }}{{- end }}`)}
+				chartFS["charts/sub2/Chart.yaml"] = &fstest.MapFile{Data: []byte("apiVersion: v2\nname: sub2\nversion: 0.1.0")}
+				chartFS["charts/sub2/values.yaml"] = &fstest.MapFile{Data: []byte("name: default\n")}
+				return mustLoadChart(chartFS)
+			},
+			values: map[string]interface{}{
+				"sub1": map[string]interface{}{"nmae": "typo"},
+				"sub2": map[string]interface{}{"name": "unused"},
+			},
+			expCoverage: &helm.ValuesCoverage{Charts: []helm.ChartValuesCoverage{
+				{
+					Chart:             "test-chart",
+					UnusedDefaultKeys: []string{},
+					UnusedUserKeys:    []string{},
+				},
+				{
+					Chart:             "test-chart/charts/sub1",
+					UnusedDefaultKeys: []string{},
+					UnusedUserKeys:    []string{"nmae"},
+				},
+				{
+					Chart:             "test-chart/charts/sub2",
+					UnusedDefaultKeys: []string{"name"},
+					UnusedUserKeys:    []string{"name"},
+				},
+			}},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			gotResult, err := helm.Render(context.TODO(), helm.TemplateConfig{
+				ReleaseName:    "test",
+				Chart:          test.chart(),
+				Values:         test.values,
+				ValuesCoverage: true,
+			})
+
+			if assert.NoError(err) {
+				assert.Equal(test.expCoverage, gotResult.ValuesCoverage)
+			}
+		})
+	}
+}
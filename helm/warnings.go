@@ -0,0 +1,281 @@
+package helm
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"helm.sh/helm/v4/pkg/chart/common"
+	chartv2 "helm.sh/helm/v4/pkg/chart/v2"
+)
+
+// RenderWarningType is the type of a render warning.
+type RenderWarningType string
+
+const (
+	// RenderWarningTypeValuesTypeMismatch is used when a value can't be merged with another one
+	// due to incompatible types (e.g: a table and a string), so one of them is ignored.
+	RenderWarningTypeValuesTypeMismatch RenderWarningType = "values-type-mismatch"
+	// RenderWarningTypeValuesNullTable is used when a null value removes a table of the chart default values.
+	RenderWarningTypeValuesNullTable RenderWarningType = "values-null-table"
+	// RenderWarningTypeDeprecatedChart is used when the chart or any of its subcharts is deprecated.
+	RenderWarningTypeDeprecatedChart RenderWarningType = "deprecated-chart"
+)
+
+// RenderWarning is a non fatal issue found while rendering a chart.
+type RenderWarning struct {
+	// Type is the type of the warning.
+	Type RenderWarningType
+	// Chart is the path of the chart that has the warning (e.g: `mychart`, `mychart/charts/sub`).
+	Chart string
+	// Path is the values path related with the warning (e.g: `sub.image`), empty if the warning
+	// is not related with the values.
+	Path string
+	// Message is the description of the warning.
+	Message string
+}
+
+func (w RenderWarning) String() string {
+	if w.Path == "" {
+		return fmt.Sprintf("%s: %s (%s)", w.Chart, w.Message, w.Type)
+	}
+
+	return fmt.Sprintf("%s: %s: %s (%s)", w.Chart, w.Path, w.Message, w.Type)
+}
+
+// valuesWarnings returns the warnings of coalescing the user values with the chart default values: the
+// values that are skipped due to incompatible types and the null values that remove chart default tables.
+// Helm only logs (or silently ignores) these, so the values are compared with the chart defaults following
+// the Helm coalescing order. The chart must have the dependencies already processed.
+func valuesWarnings(chart *chartv2.Chart, values map[string]interface{}) []RenderWarning {
+	warnings := typeMismatchWarnings(chart, chart, values, nil)
+	warnings = append(warnings, nullTablesWarnings(chart, chart, values, nil)...)
+
+	// The parent and subchart default values can have the same mismatches.
+	slices.SortStableFunc(warnings, func(a, b RenderWarning) int {
+		return cmp.Or(strings.Compare(a.Chart, b.Chart), strings.Compare(a.Path, b.Path), strings.Compare(string(a.Type), string(b.Type)))
+	})
+
+	return slices.Compact(warnings)
+}
+
+// typeMismatchWarnings returns the warnings of the values that Helm skips because the user (or parent chart)
+// values and the chart default values have incompatible types, for the chart and its subcharts.
+func typeMismatchWarnings(root, chart *chartv2.Chart, values map[string]interface{}, prefix []fieldPathSegment) []RenderWarning {
+	newWarning := func(path []fieldPathSegment, msg string) RenderWarning {
+		c, p := valuesFullKeyChart(root, root.Name()+"."+formatFieldPath(path))
+		return RenderWarning{Type: RenderWarningTypeValuesTypeMismatch, Chart: c.ChartFullPath(), Path: p, Message: msg}
+	}
+
+	// Helm only warns about the chart values root keys when the value is a table.
+	var warnings []RenderWarning
+	for _, key := range slices.Sorted(maps.Keys(chart.Values)) {
+		value, ok := values[key].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		path := append(slices.Clip(prefix), fieldPathSegment{key: key})
+		switch d := chart.Values[key].(type) {
+		case nil:
+		case map[string]interface{}:
+			warnings = append(warnings, tableTypeMismatchWarnings(d, value, path, newWarning)...)
+		default:
+			warnings = append(warnings, newWarning(path, "chart default value is skipped because it's not a table"))
+		}
+	}
+
+	// The subcharts receive the coalesced values of the parent chart, including the globals.
+	coalesced := coalescedValues(values, chart.Values)
+	for _, dep := range chart.Dependencies() {
+		sub, ok := coalesced[dep.Name()].(map[string]interface{})
+		if !ok {
+			sub = map[string]interface{}{}
+		}
+
+		path := append(slices.Clip(prefix), fieldPathSegment{key: dep.Name()})
+		sub, globalsWarnings := coalescedGlobals(sub, coalesced, path, newWarning)
+		warnings = append(warnings, globalsWarnings...)
+		warnings = append(warnings, typeMismatchWarnings(root, dep, sub, path)...)
+	}
+
+	return warnings
+}
+
+// tableTypeMismatchWarnings returns the warnings of the values table keys that have incompatible types
+// with the chart default values table keys.
+func tableTypeMismatchWarnings(defaults, values map[string]interface{}, prefix []fieldPathSegment, newWarning func([]fieldPathSegment, string) RenderWarning) []RenderWarning {
+	var warnings []RenderWarning
+	for _, key := range slices.Sorted(maps.Keys(defaults)) {
+		value, ok := values[key]
+		if !ok || value == nil {
+			continue
+		}
+
+		path := append(slices.Clip(prefix), fieldPathSegment{key: key})
+		defaultTable, defaultIsTable := defaults[key].(map[string]interface{})
+		table, isTable := value.(map[string]interface{})
+		switch {
+		case defaultIsTable && isTable:
+			warnings = append(warnings, tableTypeMismatchWarnings(defaultTable, table, path, newWarning)...)
+		case defaultIsTable:
+			warnings = append(warnings, newWarning(path, "chart default table is skipped because the value is not a table"))
+		case isTable && defaults[key] != nil:
+			warnings = append(warnings, newWarning(path, "chart default value is skipped because the value is a table"))
+		}
+	}
+
+	return warnings
+}
+
+// coalescedGlobals returns the subchart values with the parent chart globals, the same way Helm does it,
+// and the warnings of the globals that are skipped.
+func coalescedGlobals(values, parent map[string]interface{}, prefix []fieldPathSegment, newWarning func([]fieldPathSegment, string) RenderWarning) (map[string]interface{}, []RenderWarning) {
+	path := append(slices.Clip(prefix), fieldPathSegment{key: common.GlobalKey})
+
+	globals := map[string]interface{}{}
+	if g, ok := values[common.GlobalKey]; ok {
+		table, ok := g.(map[string]interface{})
+		if !ok {
+			return values, []RenderWarning{newWarning(path, "globals are skipped because the subchart globals are not a table")}
+		}
+		maps.Copy(globals, table)
+	}
+
+	parentGlobals := map[string]interface{}{}
+	if g, ok := parent[common.GlobalKey]; ok {
+		table, ok := g.(map[string]interface{})
+		if !ok {
+			return values, []RenderWarning{newWarning(path, "globals are skipped because the parent globals are not a table")}
+		}
+		parentGlobals = table
+	}
+
+	var warnings []RenderWarning
+	for _, key := range slices.Sorted(maps.Keys(parentGlobals)) {
+		global, ok := globals[key]
+		_, isTable := global.(map[string]interface{})
+		switch parentTable, parentIsTable := parentGlobals[key].(map[string]interface{}); {
+		case parentIsTable && ok && !isTable:
+			warnings = append(warnings, newWarning(append(slices.Clip(path), fieldPathSegment{key: key}), "parent global table is skipped because the subchart global is not a table"))
+		case parentIsTable && ok:
+			// The parent chart globals have precedence.
+			warnings = append(warnings, tableTypeMismatchWarnings(global.(map[string]interface{}), parentTable, append(slices.Clip(path), fieldPathSegment{key: key}), newWarning)...)
+			globals[key] = coalescedValues(parentTable, global.(map[string]interface{}))
+		case !parentIsTable && isTable:
+			warnings = append(warnings, newWarning(append(slices.Clip(path), fieldPathSegment{key: key}), "parent global value is skipped because the subchart global is a table"))
+		default:
+			globals[key] = parentGlobals[key]
+		}
+	}
+
+	values = maps.Clone(values)
+	values[common.GlobalKey] = globals
+
+	return values, warnings
+}
+
+// coalescedValues returns the values coalesced with the default values without modifying them, the values
+// have precedence, the tables are merged and the null values remove the default values.
+func coalescedValues(values, defaults map[string]interface{}) map[string]interface{} {
+	coalesced := maps.Clone(values)
+	if coalesced == nil {
+		coalesced = map[string]interface{}{}
+	}
+
+	for key, d := range defaults {
+		value, ok := coalesced[key]
+		switch {
+		case !ok:
+			coalesced[key] = d
+		case value == nil:
+			delete(coalesced, key)
+		default:
+			table, isTable := value.(map[string]interface{})
+			defaultTable, defaultIsTable := d.(map[string]interface{})
+			if isTable && defaultIsTable {
+				coalesced[key] = coalescedValues(table, defaultTable)
+			}
+		}
+	}
+
+	return coalesced
+}
+
+// valuesFullKeyChart returns the chart and the values path (relative to the root chart) of a Helm
+// values full key (e.g: `mychart.sub.image.tag`).
+func valuesFullKeyChart(chart *chartv2.Chart, key string) (*chartv2.Chart, string) {
+	path := strings.TrimPrefix(key, chart.Name()+".")
+
+	c, rest := chart, path
+	for found := true; found; {
+		found = false
+		for _, dep := range c.Dependencies() {
+			if after, ok := strings.CutPrefix(rest, dep.Name()+"."); ok {
+				c, rest, found = dep, after, true
+				break
+			}
+		}
+	}
+
+	return c, path
+}
+
+// nullTablesWarnings returns the warnings of the user null values that remove the chart and
+// subcharts default tables, the parent charts default values of the subcharts are included.
+func nullTablesWarnings(root, chart *chartv2.Chart, values map[string]interface{}, prefix []fieldPathSegment) []RenderWarning {
+	warnings := nullTableValuesWarnings(root, chart.Values, values, prefix)
+	for _, dep := range chart.Dependencies() {
+		if table, ok := values[dep.Name()].(map[string]interface{}); ok {
+			warnings = append(warnings, nullTablesWarnings(root, dep, table, append(slices.Clip(prefix), fieldPathSegment{key: dep.Name()}))...)
+		}
+	}
+
+	return warnings
+}
+
+// nullTableValuesWarnings returns the warnings of the user null values that remove the default tables,
+// the warnings are set to the chart that owns the values path.
+func nullTableValuesWarnings(root *chartv2.Chart, defaults, values map[string]interface{}, prefix []fieldPathSegment) []RenderWarning {
+	var warnings []RenderWarning
+	for _, key := range slices.Sorted(maps.Keys(defaults)) {
+		defaultTable, ok := defaults[key].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		value, ok := values[key]
+		if !ok {
+			continue
+		}
+
+		path := append(slices.Clip(prefix), fieldPathSegment{key: key})
+		if value == nil {
+			chart, p := valuesFullKeyChart(root, root.Name()+"."+formatFieldPath(path))
+			warnings = append(warnings, RenderWarning{Type: RenderWarningTypeValuesNullTable, Chart: chart.ChartFullPath(), Path: p, Message: "null value removes the chart default table"})
+			continue
+		}
+
+		if table, ok := value.(map[string]interface{}); ok {
+			warnings = append(warnings, nullTableValuesWarnings(root, defaultTable, table, path)...)
+		}
+	}
+
+	return warnings
+}
+
+// deprecatedChartWarnings returns the warnings of the deprecated charts (the chart and its subcharts).
+func deprecatedChartWarnings(chart *chartv2.Chart) []RenderWarning {
+	var warnings []RenderWarning
+	if chart.Metadata != nil && chart.Metadata.Deprecated {
+		warnings = append(warnings, RenderWarning{Type: RenderWarningTypeDeprecatedChart, Chart: chart.ChartFullPath(), Message: "chart is deprecated"})
+	}
+
+	for _, dep := range chart.Dependencies() {
+		warnings = append(warnings, deprecatedChartWarnings(dep)...)
+	}
+
+	return warnings
+}
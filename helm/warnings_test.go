@@ -0,0 +1,123 @@
+package helm_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/go-helm-template/helm"
+)
+
+func newTestWarningsChart() *helm.Chart {
+	chartFS := newTestChartFS()
+	chartFS["values.yaml"] = &fstest.MapFile{Data: []byte("image:\n  tag: v1\nresources:\n  limits:\n    cpu: 1\nname: test\nsub1:\n  config:\n    enabled: true\n")}
+	chartFS["templates/cm.yaml"] = &fstest.MapFile{Data: []byte(`name: {{ .Values.name }}`)}
+	chartFS["charts/sub1/Chart.yaml"] = &fstest.MapFile{Data: []byte("apiVersion: v2\nname: sub1\nversion: 0.1.0\ndeprecated: true")}
+	chartFS["charts/sub1/values.yaml"] = &fstest.MapFile{Data: []byte("config:\n  enabled: true\n")}
+	chartFS["charts/sub1/templates/cm.yaml"] = &fstest.MapFile{Data: []byte(`enabled: {{ .Values.config }}`)}
+
+	return mustLoadChart(chartFS)
+}
+
+func TestRenderWarnings(t *testing.T) {
+	tests := map[string]struct {
+		values      map[string]interface{}
+		expWarnings []helm.RenderWarning
+		expLogs     string
+	}{
+		"Without values issues, only the deprecated charts should be warned.": {
+			expWarnings: []helm.RenderWarning{
+				{Type: helm.RenderWarningTypeDeprecatedChart, Chart: "test-chart/charts/sub1", Message: "chart is deprecated"},
+			},
+			expLogs: `level=WARN msg="chart is deprecated" type=deprecated-chart chart=test-chart/charts/sub1 path=""` + "\n",
+		},
+
+		"Values with incompatible types should be warned.": {
+			values: map[string]interface{}{
+				"resources": map[string]interface{}{"limits": "1"},
+				"name":      map[string]interface{}{"first": "test"},
+				"sub1":      map[string]interface{}{"config": "enabled"},
+			},
+			expWarnings: []helm.RenderWarning{
+				{Type: helm.RenderWarningTypeValuesTypeMismatch, Chart: "test-chart", Path: "name", Message: "chart default value is skipped because it's not a table"},
+				{Type: helm.RenderWarningTypeValuesTypeMismatch, Chart: "test-chart", Path: "resources.limits", Message: "chart default table is skipped because the value is not a table"},
+				{Type: helm.RenderWarningTypeValuesTypeMismatch, Chart: "test-chart/charts/sub1", Path: "sub1.config", Message: "chart default table is skipped because the value is not a table"},
+				{Type: helm.RenderWarningTypeDeprecatedChart, Chart: "test-chart/charts/sub1", Message: "chart is deprecated"},
+			},
+			expLogs: `level=WARN msg="chart default value is skipped because it's not a table" type=values-type-mismatch chart=test-chart path=name` + "\n" +
+				`level=WARN msg="chart default table is skipped because the value is not a table" type=values-type-mismatch chart=test-chart path=resources.limits` + "\n" +
+				`level=WARN msg="chart default table is skipped because the value is not a table" type=values-type-mismatch chart=test-chart/charts/sub1 path=sub1.config` + "\n" +
+				`level=WARN msg="chart is deprecated" type=deprecated-chart chart=test-chart/charts/sub1 path=""` + "\n",
+		},
+
+		"Globals with incompatible types should be warned.": {
+			values: map[string]interface{}{
+				"global": "test",
+			},
+			expWarnings: []helm.RenderWarning{
+				{Type: helm.RenderWarningTypeValuesTypeMismatch, Chart: "test-chart/charts/sub1", Path: "sub1.global", Message: "globals are skipped because the parent globals are not a table"},
+				{Type: helm.RenderWarningTypeDeprecatedChart, Chart: "test-chart/charts/sub1", Message: "chart is deprecated"},
+			},
+			expLogs: `level=WARN msg="globals are skipped because the parent globals are not a table" type=values-type-mismatch chart=test-chart/charts/sub1 path=sub1.global` + "\n" +
+				`level=WARN msg="chart is deprecated" type=deprecated-chart chart=test-chart/charts/sub1 path=""` + "\n",
+		},
+
+		"Null values removing default tables should be warned.": {
+			values: map[string]interface{}{
+				"resources": map[string]interface{}{"limits": nil},
+				"sub1":      map[string]interface{}{"config": nil},
+			},
+			expWarnings: []helm.RenderWarning{
+				{Type: helm.RenderWarningTypeValuesNullTable, Chart: "test-chart", Path: "resources.limits", Message: "null value removes the chart default table"},
+				{Type: helm.RenderWarningTypeValuesNullTable, Chart: "test-chart/charts/sub1", Path: "sub1.config", Message: "null value removes the chart default table"},
+				{Type: helm.RenderWarningTypeDeprecatedChart, Chart: "test-chart/charts/sub1", Message: "chart is deprecated"},
+			},
+			expLogs: `level=WARN msg="null value removes the chart default table" type=values-null-table chart=test-chart path=resources.limits` + "\n" +
+				`level=WARN msg="null value removes the chart default table" type=values-null-table chart=test-chart/charts/sub1 path=sub1.config` + "\n" +
+				`level=WARN msg="chart is deprecated" type=deprecated-chart chart=test-chart/charts/sub1 path=""` + "\n",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var logs bytes.Buffer
+			logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{
+				Level: slog.LevelWarn,
+				ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+					if a.Key == slog.TimeKey {
+						return slog.Attr{}
+					}
+					return a
+				},
+			}))
+
+			gotResult, err := helm.Render(context.TODO(), helm.TemplateConfig{
+				ReleaseName: "test",
+				Chart:       newTestWarningsChart(),
+				Values:      test.values,
+				Logger:      logger,
+			})
+
+			if assert.NoError(err) {
+				assert.Equal(test.expWarnings, gotResult.Warnings)
+				assert.Equal(test.expLogs, logs.String())
+			}
+
+			// The warnings don't depend on the logger.
+			gotResult, err = helm.Render(context.TODO(), helm.TemplateConfig{
+				ReleaseName: "test",
+				Chart:       newTestWarningsChart(),
+				Values:      test.values,
+			})
+			if assert.NoError(err) {
+				assert.Equal(test.expWarnings, gotResult.Warnings)
+			}
+		})
+	}
+}
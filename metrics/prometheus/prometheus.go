@@ -0,0 +1,144 @@
+/*
+Package prometheus implements the go-helm-template metrics recorder as a Prometheus collector.
+*/
+package prometheus
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/slok/go-helm-template/helm"
+)
+
+const defaultPrefix = "go_helm_template"
+
+// Config is the configuration of the Prometheus recorder.
+type Config struct {
+	// Prefix is the prefix of the metrics names.
+	// By default `go_helm_template`.
+	Prefix string
+	// DurationBuckets are the chart load and render duration histograms buckets (in seconds).
+	// By default `prometheus.DefBuckets`.
+	DurationBuckets []float64
+	// OutputBytesBuckets are the rendered manifests size histogram buckets.
+	// By default exponential buckets from 1KiB to 16MiB.
+	OutputBytesBuckets []float64
+}
+
+func (c *Config) defaults() {
+	if c.Prefix == "" {
+		c.Prefix = defaultPrefix
+	}
+
+	if len(c.DurationBuckets) == 0 {
+		c.DurationBuckets = prometheus.DefBuckets
+	}
+
+	if len(c.OutputBytesBuckets) == 0 {
+		c.OutputBytesBuckets = prometheus.ExponentialBuckets(1024, 4, 8)
+	}
+}
+
+// Recorder is a helm metrics recorder that is also a Prometheus collector, so it needs to be registered
+// on a Prometheus registry (e.g: `prometheus.MustRegister(recorder)`).
+type Recorder struct {
+	chartLoadDuration *prometheus.HistogramVec
+	renders           *prometheus.CounterVec
+	renderFailures    *prometheus.CounterVec
+	renderDuration    *prometheus.HistogramVec
+	renderOutputBytes *prometheus.HistogramVec
+}
+
+var (
+	_ helm.MetricsRecorder = &Recorder{}
+	_ prometheus.Collector = &Recorder{}
+)
+
+// NewRecorder returns a new Prometheus recorder.
+func NewRecorder(config Config) *Recorder {
+	config.defaults()
+
+	return &Recorder{
+		chartLoadDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: config.Prefix,
+			Subsystem: "chart",
+			Name:      "load_duration_seconds",
+			Help:      "The duration of the chart loads.",
+			Buckets:   config.DurationBuckets,
+		}, []string{"chart", "success"}),
+
+		renders: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: config.Prefix,
+			Subsystem: "chart",
+			Name:      "renders_total",
+			Help:      "The total number of chart renders.",
+		}, []string{"chart"}),
+
+		renderFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: config.Prefix,
+			Subsystem: "chart",
+			Name:      "render_failures_total",
+			Help:      "The total number of failed chart renders by reason.",
+		}, []string{"chart", "reason"}),
+
+		renderDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: config.Prefix,
+			Subsystem: "chart",
+			Name:      "render_duration_seconds",
+			Help:      "The duration of the chart renders.",
+			Buckets:   config.DurationBuckets,
+		}, []string{"chart", "success"}),
+
+		renderOutputBytes: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: config.Prefix,
+			Subsystem: "chart",
+			Name:      "render_output_bytes",
+			Help:      "The size of the rendered manifests.",
+			Buckets:   config.OutputBytesBuckets,
+		}, []string{"chart"}),
+	}
+}
+
+// ObserveChartLoad satisfies helm.MetricsRecorder interface.
+func (r *Recorder) ObserveChartLoad(ctx context.Context, m helm.ChartLoadMetrics) {
+	r.chartLoadDuration.WithLabelValues(m.Chart, success(m.FailureReason)).Observe(m.Duration.Seconds())
+}
+
+// ObserveRender satisfies helm.MetricsRecorder interface.
+func (r *Recorder) ObserveRender(ctx context.Context, m helm.RenderMetrics) {
+	r.renders.WithLabelValues(m.Chart).Inc()
+	r.renderDuration.WithLabelValues(m.Chart, success(m.FailureReason)).Observe(m.Duration.Seconds())
+
+	if m.FailureReason != "" {
+		r.renderFailures.WithLabelValues(m.Chart, string(m.FailureReason)).Inc()
+		return
+	}
+	r.renderOutputBytes.WithLabelValues(m.Chart).Observe(float64(m.OutputBytes))
+}
+
+// Describe satisfies prometheus.Collector interface.
+func (r *Recorder) Describe(ch chan<- *prometheus.Desc) {
+	r.chartLoadDuration.Describe(ch)
+	r.renders.Describe(ch)
+	r.renderFailures.Describe(ch)
+	r.renderDuration.Describe(ch)
+	r.renderOutputBytes.Describe(ch)
+}
+
+// Collect satisfies prometheus.Collector interface.
+func (r *Recorder) Collect(ch chan<- prometheus.Metric) {
+	r.chartLoadDuration.Collect(ch)
+	r.renders.Collect(ch)
+	r.renderFailures.Collect(ch)
+	r.renderDuration.Collect(ch)
+	r.renderOutputBytes.Collect(ch)
+}
+
+func success(reason helm.FailureReason) string {
+	if reason == "" {
+		return "true"
+	}
+
+	return "false"
+}
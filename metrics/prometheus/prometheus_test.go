@@ -0,0 +1,94 @@
+package prometheus_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/go-helm-template/helm"
+	helmprometheus "github.com/slok/go-helm-template/metrics/prometheus"
+)
+
+func TestRecorder(t *testing.T) {
+	tests := map[string]struct {
+		config     helmprometheus.Config
+		record     func(r *helmprometheus.Recorder)
+		metrics    []string
+		expMetrics string
+	}{
+		"Chart loads should be recorded.": {
+			config: helmprometheus.Config{DurationBuckets: []float64{1}},
+			record: func(r *helmprometheus.Recorder) {
+				r.ObserveChartLoad(context.TODO(), helm.ChartLoadMetrics{Chart: "test-chart", Duration: 500 * time.Millisecond})
+				r.ObserveChartLoad(context.TODO(), helm.ChartLoadMetrics{Duration: 2 * time.Second, FailureReason: helm.FailureReasonLoad})
+			},
+			metrics: []string{"go_helm_template_chart_load_duration_seconds"},
+			expMetrics: `
+# HELP go_helm_template_chart_load_duration_seconds The duration of the chart loads.
+# TYPE go_helm_template_chart_load_duration_seconds histogram
+go_helm_template_chart_load_duration_seconds_bucket{chart="",success="false",le="1"} 0
+go_helm_template_chart_load_duration_seconds_bucket{chart="",success="false",le="+Inf"} 1
+go_helm_template_chart_load_duration_seconds_sum{chart="",success="false"} 2
+go_helm_template_chart_load_duration_seconds_count{chart="",success="false"} 1
+go_helm_template_chart_load_duration_seconds_bucket{chart="test-chart",success="true",le="1"} 1
+go_helm_template_chart_load_duration_seconds_bucket{chart="test-chart",success="true",le="+Inf"} 1
+go_helm_template_chart_load_duration_seconds_sum{chart="test-chart",success="true"} 0.5
+go_helm_template_chart_load_duration_seconds_count{chart="test-chart",success="true"} 1
+`,
+		},
+
+		"Renders should be recorded with custom prefix.": {
+			config: helmprometheus.Config{Prefix: "test", DurationBuckets: []float64{1}, OutputBytesBuckets: []float64{100}},
+			record: func(r *helmprometheus.Recorder) {
+				r.ObserveRender(context.TODO(), helm.RenderMetrics{Chart: "test-chart", Duration: 500 * time.Millisecond, OutputBytes: 54})
+				r.ObserveRender(context.TODO(), helm.RenderMetrics{Chart: "test-chart", Duration: 250 * time.Millisecond, FailureReason: helm.FailureReasonMissingValue})
+				r.ObserveRender(context.TODO(), helm.RenderMetrics{Chart: "test-chart", Duration: 250 * time.Millisecond, FailureReason: helm.FailureReasonMissingValue})
+			},
+			metrics: []string{"test_chart_renders_total", "test_chart_render_failures_total", "test_chart_render_duration_seconds", "test_chart_render_output_bytes"},
+			expMetrics: `
+# HELP test_chart_render_duration_seconds The duration of the chart renders.
+# TYPE test_chart_render_duration_seconds histogram
+test_chart_render_duration_seconds_bucket{chart="test-chart",success="false",le="1"} 2
+test_chart_render_duration_seconds_bucket{chart="test-chart",success="false",le="+Inf"} 2
+test_chart_render_duration_seconds_sum{chart="test-chart",success="false"} 0.5
+test_chart_render_duration_seconds_count{chart="test-chart",success="false"} 2
+test_chart_render_duration_seconds_bucket{chart="test-chart",success="true",le="1"} 1
+test_chart_render_duration_seconds_bucket{chart="test-chart",success="true",le="+Inf"} 1
+test_chart_render_duration_seconds_sum{chart="test-chart",success="true"} 0.5
+test_chart_render_duration_seconds_count{chart="test-chart",success="true"} 1
+# HELP test_chart_render_failures_total The total number of failed chart renders by reason.
+# TYPE test_chart_render_failures_total counter
+test_chart_render_failures_total{chart="test-chart",reason="missing-value"} 2
+# HELP test_chart_render_output_bytes The size of the rendered manifests.
+# TYPE test_chart_render_output_bytes histogram
+test_chart_render_output_bytes_bucket{chart="test-chart",le="100"} 1
+test_chart_render_output_bytes_bucket{chart="test-chart",le="+Inf"} 1
+test_chart_render_output_bytes_sum{chart="test-chart"} 54
+test_chart_render_output_bytes_count{chart="test-chart"} 1
+# HELP test_chart_renders_total The total number of chart renders.
+# TYPE test_chart_renders_total counter
+test_chart_renders_total{chart="test-chart"} 3
+`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			reg := prometheus.NewRegistry()
+			recorder := helmprometheus.NewRecorder(test.config)
+			reg.MustRegister(recorder)
+
+			test.record(recorder)
+
+			err := testutil.GatherAndCompare(reg, strings.NewReader(test.expMetrics), test.metrics...)
+			assert.NoError(err)
+		})
+	}
+}